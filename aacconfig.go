@@ -0,0 +1,278 @@
+package rtspclient
+
+import (
+	"encoding/hex"
+	"errors"
+)
+
+var aacSampleRates = []int{
+	96000, 88200, 64000, 48000, 44100, 32000, 24000, 22050,
+	16000, 12000, 11025, 8000, 7350,
+}
+
+// AACConfig is a decoded MPEG-4 AudioSpecificConfig (ISO/IEC 14496-3 1.6.2.1).
+type AACConfig struct {
+	ObjectType          int // audio object type, 2 is AAC LC
+	SampleRateIndex     int // 0x0f if the rate is given explicitly
+	SampleRate          int // core sampling rate
+	ExtensionSampleRate int // SBR output rate, 0 without explicit SBR signalling
+	Channels            int // channel configuration, 0 means defined in stream
+	ProgramChannels     int // channels of the program_config_element when Channels is 0
+	FrameLength         int // core samples per access unit, 1024 or 960
+	Raw                 []byte
+}
+
+// ParseAACConfig decodes an AudioSpecificConfig.
+func ParseAACConfig(src []byte) (*AACConfig, error) {
+	config, err := readAACConfig(newBitReader(src))
+	if nil != err {
+		return nil, err
+	}
+	config.Raw = src
+	return config, nil
+}
+
+// readAACConfig reads an AudioSpecificConfig that need not be byte aligned,
+// as embedded in a LATM StreamMuxConfig. Raw is left for the caller.
+func readAACConfig(reader *bitReader) (*AACConfig, error) {
+	config := &AACConfig{FrameLength: 1024}
+	start := reader.offset
+
+	objectType, err := readAACObjectType(reader)
+	if nil != err {
+		return nil, err
+	}
+	config.ObjectType = objectType
+	config.SampleRateIndex, config.SampleRate, err = readAACSampleRate(reader)
+	if nil != err {
+		return nil, err
+	}
+	channels, err := reader.readBits(4)
+	if nil != err {
+		return nil, err
+	}
+	config.Channels = int(channels)
+
+	// explicit SBR / PS signalling
+	if 5 == config.ObjectType || 29 == config.ObjectType {
+		_, config.ExtensionSampleRate, err = readAACSampleRate(reader)
+		if nil != err {
+			return nil, err
+		}
+		config.ObjectType, err = readAACObjectType(reader)
+		if nil != err {
+			return nil, err
+		}
+	}
+
+	switch config.ObjectType {
+	case 1, 2, 3, 4, 6, 7, 17, 19, 20, 21, 22, 23:
+		if err := readGASpecificConfig(reader, config, start); nil != err {
+			return nil, err
+		}
+	}
+	switch config.ObjectType {
+	case 17, 19, 20, 21, 22, 23, 24, 25, 26, 27, 39:
+		// epConfig
+		if err := reader.skipBits(2); nil != err {
+			return nil, err
+		}
+	}
+	return config, nil
+}
+
+// readGASpecificConfig reads a GASpecificConfig of the AudioSpecificConfig
+// starting at bit start.
+func readGASpecificConfig(reader *bitReader, config *AACConfig, start int) error {
+	frameLengthFlag, err := reader.readFlag()
+	if nil != err {
+		return err
+	}
+	if frameLengthFlag {
+		config.FrameLength = 960
+	}
+	if 23 == config.ObjectType {
+		config.FrameLength /= 2
+	}
+
+	dependsOnCoreCoder, err := reader.readFlag()
+	if nil != err {
+		return err
+	}
+	if dependsOnCoreCoder {
+		// coreCoderDelay
+		if err := reader.skipBits(14); nil != err {
+			return err
+		}
+	}
+	extensionFlag, err := reader.readFlag()
+	if nil != err {
+		return err
+	}
+	if 0 == config.Channels {
+		if config.ProgramChannels, err = readProgramConfigElement(reader, start); nil != err {
+			return err
+		}
+	}
+	if 6 == config.ObjectType || 20 == config.ObjectType {
+		// layerNr
+		if err := reader.skipBits(3); nil != err {
+			return err
+		}
+	}
+	if extensionFlag {
+		switch config.ObjectType {
+		case 22:
+			// numOfSubFrame, layer_length
+			if err := reader.skipBits(16); nil != err {
+				return err
+			}
+		case 17, 19, 20, 23:
+			// aacSectionDataResilienceFlag, aacScalefactorDataResilienceFlag,
+			// aacSpectralDataResilienceFlag
+			if err := reader.skipBits(3); nil != err {
+				return err
+			}
+		}
+		// extensionFlag3
+		if err := reader.skipBits(1); nil != err {
+			return err
+		}
+	}
+	return nil
+}
+
+// readProgramConfigElement skips a program_config_element (ISO/IEC 14496-3
+// 4.4.1.1) and returns its number of channels. Its byte alignment is relative
+// to the AudioSpecificConfig starting at bit start.
+func readProgramConfigElement(reader *bitReader, start int) (int, error) {
+	// element_instance_tag, object_type, sampling_frequency_index
+	if err := reader.skipBits(10); nil != err {
+		return 0, err
+	}
+	var counts [6]uint32
+	for i, bits := range []int{4, 4, 4, 2, 3, 4} {
+		count, err := reader.readBits(bits)
+		if nil != err {
+			return 0, err
+		}
+		counts[i] = count
+	}
+	front, side, back, lfe, assocData, validCC := counts[0], counts[1], counts[2], counts[3], counts[4], counts[5]
+
+	// mono_mixdown, stereo_mixdown, matrix_mixdown
+	for _, bits := range []int{4, 4, 3} {
+		present, err := reader.readFlag()
+		if nil != err {
+			return 0, err
+		}
+		if present {
+			if err := reader.skipBits(bits); nil != err {
+				return 0, err
+			}
+		}
+	}
+
+	channels := int(lfe)
+	for i := uint32(0); i < front+side+back; i++ {
+		isCPE, err := reader.readFlag()
+		if nil != err {
+			return 0, err
+		}
+		channels++
+		if isCPE {
+			channels++
+		}
+		// element_tag_select
+		if err := reader.skipBits(4); nil != err {
+			return 0, err
+		}
+	}
+	// lfe, assoc_data and cc element tags
+	if err := reader.skipBits(int(lfe)*4 + int(assocData)*4 + int(validCC)*5); nil != err {
+		return 0, err
+	}
+
+	// byte_alignment
+	if err := reader.skipBits((8 - (reader.offset-start)%8) % 8); nil != err {
+		return 0, err
+	}
+	commentLen, err := reader.readBits(8)
+	if nil != err {
+		return 0, err
+	}
+	if err := reader.skipBits(int(commentLen) * 8); nil != err {
+		return 0, err
+	}
+	return channels, nil
+}
+
+// ParseAACConfigString decodes a hex AudioSpecificConfig as found in the
+// "config" fmtp parameter.
+func ParseAACConfigString(config string) (*AACConfig, error) {
+	src, err := hex.DecodeString(config)
+	if nil != err {
+		return nil, err
+	}
+	return ParseAACConfig(src)
+}
+
+// outputTiming returns the decoded sampling rate and samples per access unit,
+// taking SBR into account.
+func (config *AACConfig) outputTiming() (int, int) {
+	if 0 < config.ExtensionSampleRate && 0 < config.SampleRate {
+		return config.ExtensionSampleRate, config.FrameLength * config.ExtensionSampleRate / config.SampleRate
+	}
+	return config.SampleRate, config.FrameLength
+}
+
+// ADTSHeader returns the 7 byte ADTS header for a raw frame of frameLen bytes,
+// or nil if the config cannot be expressed in ADTS.
+func (config *AACConfig) ADTSHeader(frameLen int) []byte {
+	if config.ObjectType < 1 || config.ObjectType > 4 || config.SampleRateIndex >= len(aacSampleRates) {
+		return nil
+	}
+	fullLen := frameLen + 7
+	header := make([]byte, 7)
+	header[0] = 0xff
+	header[1] = 0xf1 // MPEG-4, layer 0, no CRC
+	header[2] = byte((config.ObjectType-1)<<6) | byte(config.SampleRateIndex<<2) | byte((config.Channels>>2)&0x01)
+	header[3] = byte((config.Channels&0x03)<<6) | byte((fullLen>>11)&0x03)
+	header[4] = byte(fullLen >> 3)
+	header[5] = byte((fullLen&0x07)<<5) | 0x1f
+	header[6] = 0xfc
+	return header
+}
+
+func readAACObjectType(reader *bitReader) (int, error) {
+	objectType, err := reader.readBits(5)
+	if nil != err {
+		return 0, err
+	}
+	if 31 == objectType {
+		objectTypeExt, err := reader.readBits(6)
+		if nil != err {
+			return 0, err
+		}
+		objectType = 32 + objectTypeExt
+	}
+	return int(objectType), nil
+}
+
+func readAACSampleRate(reader *bitReader) (int, int, error) {
+	index, err := reader.readBits(4)
+	if nil != err {
+		return 0, 0, err
+	}
+	if 0x0f == index {
+		sampleRate, err := reader.readBits(24)
+		if nil != err {
+			return 0, 0, err
+		}
+		return int(index), int(sampleRate), nil
+	}
+	if int(index) >= len(aacSampleRates) {
+		return 0, 0, errors.New("aac config: invalid sampling frequency index")
+	}
+	return int(index), aacSampleRates[index], nil
+}
@@ -0,0 +1,218 @@
+package rtspclient
+
+import (
+	"encoding/binary"
+	"strings"
+)
+
+// AacRtpParser depacketizes MPEG4-GENERIC streams (RFC 3640) such as
+// AAC-hbr and AAC-lbr. Every access unit is delivered as one frame.
+type AacRtpParser struct {
+	sizeLength              int
+	indexLength             int
+	indexDeltaLength        int
+	ctsDeltaLength          int
+	dtsDeltaLength          int
+	randomAccessIndication  bool
+	streamStateIndication   int
+	auxiliaryDataSizeLength int
+	constantSize            int
+	config                  *AACConfig
+	frameDuration           uint32 // rtp ticks per access unit
+
+	// state of the packet being parsed
+	timestamp uint32
+	auSizes   []int
+	auIndexes []int
+	auCursor  int
+}
+
+func newAacRtpParser(media MediaSubsession) *AacRtpParser {
+	rtpParser := &AacRtpParser{
+		sizeLength:              media.fmtpInt("sizelength", 0),
+		indexLength:             media.fmtpInt("indexlength", 0),
+		indexDeltaLength:        media.fmtpInt("indexdeltalength", 0),
+		ctsDeltaLength:          media.fmtpInt("ctsdeltalength", 0),
+		dtsDeltaLength:          media.fmtpInt("dtsdeltalength", 0),
+		randomAccessIndication:  media.fmtpInt("randomaccessindication", 0) == 1,
+		streamStateIndication:   media.fmtpInt("streamstateindication", 0),
+		auxiliaryDataSizeLength: media.fmtpInt("auxiliarydatasizelength", 0),
+		constantSize:            media.fmtpInt("constantsize", 0),
+		frameDuration:           1024,
+	}
+
+	// AAC-hbr and AAC-lbr fix the header layout, fill it in for servers that
+	// only send the mode
+	switch strings.ToLower(media.Fmtp["mode"]) {
+	case "aac-hbr":
+		if 0 == rtpParser.sizeLength {
+			rtpParser.sizeLength, rtpParser.indexLength, rtpParser.indexDeltaLength = 13, 3, 3
+		}
+	case "aac-lbr":
+		if 0 == rtpParser.sizeLength {
+			rtpParser.sizeLength, rtpParser.indexLength, rtpParser.indexDeltaLength = 6, 2, 2
+		}
+	}
+
+	if config, err := ParseAACConfigString(media.Fmtp["config"]); nil == err {
+		rtpParser.config = config
+		rtpParser.frameDuration = uint32(config.FrameLength)
+		if 0 < config.SampleRate && 0 < media.RtpTimestampFrequency {
+			rtpParser.frameDuration = uint32(config.FrameLength * media.RtpTimestampFrequency / config.SampleRate)
+		}
+	}
+	return rtpParser
+}
+
+// Config returns the AudioSpecificConfig from the SDP, or nil if absent.
+func (rtpParser *AacRtpParser) Config() *AACConfig {
+	return rtpParser.config
+}
+
+func (rtpParser *AacRtpParser) hasAUHeaders() bool {
+	return 0 < rtpParser.sizeLength || 0 < rtpParser.indexLength || 0 < rtpParser.indexDeltaLength ||
+		0 < rtpParser.ctsDeltaLength || 0 < rtpParser.dtsDeltaLength || rtpParser.randomAccessIndication ||
+		0 < rtpParser.streamStateIndication
+}
+
+func (rtpParser *AacRtpParser) SplitHeader(src []byte) (isCompletesFrame bool, header []byte, payload []byte) {
+	rtpParser.timestamp = binary.BigEndian.Uint32(src[4:8])
+	rtpParser.auSizes = rtpParser.auSizes[:0]
+	rtpParser.auIndexes = rtpParser.auIndexes[:0]
+	rtpParser.auCursor = 0
+	mark := (src[1] & 0x80) != 0
+
+	skipHeaderLen := 0
+	rtpData := src[RtpHeaderLen:]
+	if rtpParser.hasAUHeaders() {
+		headerLen, ok := rtpParser.parsingAUHeaders(rtpData)
+		if !ok {
+			// malformed, drop the whole payload
+			return true, src, src[len(src):]
+		}
+		skipHeaderLen = headerLen
+	} else {
+		size := len(rtpData)
+		if 0 < rtpParser.constantSize {
+			size = rtpParser.constantSize
+		}
+		rtpParser.auSizes = append(rtpParser.auSizes, size)
+		rtpParser.auIndexes = append(rtpParser.auIndexes, 0)
+	}
+
+	// a single access unit larger than the packet is fragmented, it is
+	// complete on the packet with the marker bit
+	isCompletesFrame = true
+	if 1 == len(rtpParser.auSizes) && rtpParser.auSizes[0] > len(rtpData)-skipHeaderLen {
+		isCompletesFrame = mark
+	}
+	return isCompletesFrame, src[:skipHeaderLen+RtpHeaderLen], src[skipHeaderLen+RtpHeaderLen:]
+}
+
+// parsingAUHeaders reads the AU-header and auxiliary sections and returns
+// their total length in bytes.
+func (rtpParser *AacRtpParser) parsingAUHeaders(rtpData []byte) (int, bool) {
+	if len(rtpData) < 2 {
+		return 0, false
+	}
+	headersBits := int(binary.BigEndian.Uint16(rtpData[:2]))
+	headersLen := 2 + (headersBits+7)/8
+	if len(rtpData) < headersLen {
+		return 0, false
+	}
+
+	reader := newBitReader(rtpData[2:headersLen])
+	index := 0
+	for reader.offset < headersBits {
+		size, err := reader.readBits(rtpParser.sizeLength)
+		if nil != err {
+			return 0, false
+		}
+		if 0 == len(rtpParser.auSizes) {
+			auIndex, err := reader.readBits(rtpParser.indexLength)
+			if nil != err {
+				return 0, false
+			}
+			index = int(auIndex)
+		} else {
+			auIndexDelta, err := reader.readBits(rtpParser.indexDeltaLength)
+			if nil != err {
+				return 0, false
+			}
+			index += int(auIndexDelta) + 1
+		}
+		if err := rtpParser.skipOptionalFields(reader); nil != err {
+			return 0, false
+		}
+		if 0 < rtpParser.constantSize {
+			size = uint32(rtpParser.constantSize)
+		}
+		rtpParser.auSizes = append(rtpParser.auSizes, int(size))
+		rtpParser.auIndexes = append(rtpParser.auIndexes, index)
+	}
+	if 0 < len(rtpParser.auIndexes) {
+		firstIndex := rtpParser.auIndexes[0]
+		for i := range rtpParser.auIndexes {
+			rtpParser.auIndexes[i] -= firstIndex
+		}
+	}
+
+	if 0 < rtpParser.auxiliaryDataSizeLength {
+		reader := newBitReader(rtpData[headersLen:])
+		auxiliaryBits, err := reader.readBits(rtpParser.auxiliaryDataSizeLength)
+		if nil != err {
+			return 0, false
+		}
+		headersLen += (rtpParser.auxiliaryDataSizeLength + int(auxiliaryBits) + 7) / 8
+		if len(rtpData) < headersLen {
+			return 0, false
+		}
+	}
+	return headersLen, true
+}
+
+// skipOptionalFields skips CTS, DTS, RAP and stream state of an AU-header.
+func (rtpParser *AacRtpParser) skipOptionalFields(reader *bitReader) error {
+	for _, deltaLength := range []int{rtpParser.ctsDeltaLength, rtpParser.dtsDeltaLength} {
+		if 0 == deltaLength {
+			continue
+		}
+		flag, err := reader.readFlag()
+		if nil != err {
+			return err
+		}
+		if flag {
+			if err := reader.skipBits(deltaLength); nil != err {
+				return err
+			}
+		}
+	}
+	if rtpParser.randomAccessIndication {
+		if err := reader.skipBits(1); nil != err {
+			return err
+		}
+	}
+	return reader.skipBits(rtpParser.streamStateIndication)
+}
+
+func (rtpParser *AacRtpParser) ParsingRtp(header []byte, payload []byte) (naluHeaderSize int, naluSize int) {
+	if rtpParser.auCursor >= len(rtpParser.auSizes) {
+		// data past the last access unit
+		return len(payload), len(payload)
+	}
+	naluSize = rtpParser.auSizes[rtpParser.auCursor]
+	rtpParser.auCursor++
+	return 0, naluSize
+}
+
+// FrameInfo gives every access unit of an aggregated packet its own timestamp.
+func (rtpParser *AacRtpParser) FrameInfo(data *RtspData) {
+	if nil != rtpParser.config {
+		data.SampleRate, data.Samples = rtpParser.config.outputTiming()
+	}
+	auIndex := rtpParser.auCursor - 1
+	if auIndex < 0 || auIndex >= len(rtpParser.auIndexes) {
+		return
+	}
+	data.Timestamp = rtpParser.timestamp + uint32(rtpParser.auIndexes[auIndex])*rtpParser.frameDuration
+}
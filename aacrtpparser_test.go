@@ -0,0 +1,162 @@
+package rtspclient
+
+import (
+	"bytes"
+	"encoding/binary"
+	"testing"
+)
+
+func newTestRtpPacket(mark bool, seq uint16, timestamp uint32, payload []byte) []byte {
+	packet := make([]byte, RtpHeaderLen, RtpHeaderLen+len(payload))
+	packet[0] = 0x80
+	packet[1] = 96
+	if mark {
+		packet[1] |= 0x80
+	}
+	binary.BigEndian.PutUint16(packet[2:4], seq)
+	binary.BigEndian.PutUint32(packet[4:8], timestamp)
+	binary.BigEndian.PutUint32(packet[8:12], 0x12345678)
+	return append(packet, payload...)
+}
+
+func parsingTestPackets(rtpParser *RtpParser, packets ...[]byte) []*RtspData {
+	var frames []*RtspData
+	for _, packet := range packets {
+		rtpParser.parsingPacket(packet, func(data *RtspData) {
+			frames = append(frames, data)
+		})
+	}
+	return frames
+}
+
+func newTestAacMedia() MediaSubsession {
+	return MediaSubsession{
+		MediumName:            "audio",
+		CodecName:             "mpeg4-generic",
+		RtpTimestampFrequency: 44100,
+		Fmtp:                  getFmtParame("96 streamtype=5; profile-level-id=15; mode=AAC-hbr; config=1210; SizeLength=13; IndexLength=3; IndexDeltaLength=3"),
+	}
+}
+
+// aacHbrHeader returns the AU-header section for AAC-hbr access units.
+func aacHbrHeader(sizes ...int) []byte {
+	header := make([]byte, 2+2*len(sizes))
+	binary.BigEndian.PutUint16(header, uint16(16*len(sizes)))
+	for i, size := range sizes {
+		binary.BigEndian.PutUint16(header[2+2*i:], uint16(size<<3))
+	}
+	return header
+}
+
+func TestParseAACConfig(t *testing.T) {
+	config, err := ParseAACConfigString("1210")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if config.ObjectType != 2 || config.SampleRate != 44100 || config.Channels != 2 || config.FrameLength != 1024 {
+		t.Errorf("unexpected config %+v", config)
+	}
+	header := config.ADTSHeader(100)
+	expected := []byte{0xff, 0xf1, 0x50, 0x80, 0x0d, 0x7f, 0xfc}
+	if !bytes.Equal(header, expected) {
+		t.Errorf("adts header %x, expected %x", header, expected)
+	}
+
+	// HE-AAC with explicit SBR signalling
+	config, err = ParseAACConfigString("2b920800")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if config.ObjectType != 2 || config.SampleRate != 22050 || config.ExtensionSampleRate != 44100 {
+		t.Errorf("unexpected config %+v", config)
+	}
+
+	// channel layout in a program_config_element with a comment
+	config, err = ParseAACConfigString("12000504000020026162")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if config.Channels != 0 || config.ProgramChannels != 2 || config.SampleRate != 44100 || config.FrameLength != 1024 {
+		t.Errorf("unexpected config %+v", config)
+	}
+	if _, err = ParseAACConfigString("120005040000200261"); err == nil {
+		t.Errorf("truncated comment parsed")
+	}
+}
+
+func TestAacRtpParserAggregated(t *testing.T) {
+	rtpParser := newRtpParser(newTestAacMedia())
+	au1 := bytes.Repeat([]byte{0x01}, 10)
+	au2 := bytes.Repeat([]byte{0x02}, 20)
+	payload := append(aacHbrHeader(len(au1), len(au2)), au1...)
+	payload = append(payload, au2...)
+
+	frames := parsingTestPackets(rtpParser, newTestRtpPacket(true, 1, 1000, payload))
+	if len(frames) != 2 {
+		t.Fatalf("got %d frames, expected 2", len(frames))
+	}
+	if !bytes.Equal(frames[0].Data, au1) || !bytes.Equal(frames[1].Data, au2) {
+		t.Errorf("access units not split")
+	}
+	if frames[0].Timestamp != 1000 || frames[1].Timestamp != 2024 {
+		t.Errorf("timestamps %d %d, expected 1000 2024", frames[0].Timestamp, frames[1].Timestamp)
+	}
+}
+
+func TestAacRtpParserFragmented(t *testing.T) {
+	rtpParser := newRtpParser(newTestAacMedia())
+	au := make([]byte, 300)
+	for i := range au {
+		au[i] = byte(i)
+	}
+	first := append(aacHbrHeader(len(au)), au[:200]...)
+	second := append(aacHbrHeader(len(au)), au[200:]...)
+
+	frames := parsingTestPackets(rtpParser, newTestRtpPacket(false, 1, 5000, first), newTestRtpPacket(true, 2, 5000, second))
+	if len(frames) != 1 {
+		t.Fatalf("got %d frames, expected 1", len(frames))
+	}
+	if !bytes.Equal(frames[0].Data, au) || frames[0].Timestamp != 5000 {
+		t.Errorf("fragmented access unit not reassembled")
+	}
+}
+
+func TestGetFmtParame(t *testing.T) {
+	fmtp := getFmtParame("96 packetization-mode=1;profile-level-id=42e01f;sprop-parameter-sets=Z0LgH5ZUBQHtCAAAAwAIAAADAYR4wZU=,aM48gA==")
+	if fmtp["packetization-mode"] != "1" || fmtp["profile-level-id"] != "42e01f" {
+		t.Errorf("unexpected fmtp %v", fmtp)
+	}
+	if fmtp["sprop-parameter-sets"] != "Z0LgH5ZUBQHtCAAAAwAIAAADAYR4wZU=,aM48gA==" {
+		t.Errorf("unexpected sprop-parameter-sets %q", fmtp["sprop-parameter-sets"])
+	}
+}
+
+func TestLatmRtpParser(t *testing.T) {
+	media := MediaSubsession{
+		MediumName:            "audio",
+		CodecName:             "MP4A-LATM",
+		RtpTimestampFrequency: 44100,
+		Fmtp:                  getFmtParame("96 profile-level-id=15;object=2;cpresent=0;config=400024203FC0"),
+	}
+	rtpParser := newRtpParser(media)
+	latmParser := rtpParser.rtpSourceHandler.(*LatmRtpParser)
+	config := latmParser.Config()
+	if config == nil {
+		t.Fatal("config not parsed")
+	}
+	if !bytes.Equal(config.AAC.Raw, []byte{0x12, 0x10}) || config.AAC.SampleRate != 44100 {
+		t.Errorf("unexpected AudioSpecificConfig %x %+v", config.AAC.Raw, config.AAC)
+	}
+
+	frame := bytes.Repeat([]byte{0x21}, 300)
+	element := append([]byte{0xff, 300 - 255}, frame...)
+	frames := parsingTestPackets(rtpParser,
+		newTestRtpPacket(false, 1, 3000, element[:100]),
+		newTestRtpPacket(true, 2, 3000, element[100:]))
+	if len(frames) != 1 {
+		t.Fatalf("got %d frames, expected 1", len(frames))
+	}
+	if !bytes.Equal(frames[0].Data, frame) || frames[0].Timestamp != 3000 {
+		t.Errorf("audioMuxElement not reassembled")
+	}
+}
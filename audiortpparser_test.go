@@ -0,0 +1,69 @@
+package rtspclient
+
+import (
+	"bytes"
+	"testing"
+)
+
+func TestG711Decode(t *testing.T) {
+	pcmu := DecodePCMU([]byte{0xff, 0x7f, 0x00, 0x80})
+	if pcmu[0] != 0 || pcmu[1] != 0 || pcmu[2] != -32124 || pcmu[3] != 32124 {
+		t.Errorf("unexpected mu-law samples %v", pcmu)
+	}
+	pcma := DecodePCMA([]byte{0xd5, 0x55, 0xaa, 0x2a})
+	if pcma[0] != 8 || pcma[1] != -8 || pcma[2] != 32256 || pcma[3] != -32256 {
+		t.Errorf("unexpected A-law samples %v", pcma)
+	}
+}
+
+func TestAudioFrameInfo(t *testing.T) {
+	tests := []struct {
+		media      MediaSubsession
+		payload    []byte
+		sampleRate int
+		samples    int
+	}{
+		{MediaSubsession{CodecName: "PCMA", RtpTimestampFrequency: 8000, Channels: 1}, make([]byte, 160), 8000, 160},
+		{MediaSubsession{CodecName: "G726-32", RtpTimestampFrequency: 8000, Channels: -1}, make([]byte, 80), 8000, 160},
+		{MediaSubsession{CodecName: "AAL2-G726-40", RtpTimestampFrequency: 8000, Channels: -1}, make([]byte, 100), 8000, 160},
+		{MediaSubsession{CodecName: "L16", RtpTimestampFrequency: 44100, Channels: 2}, make([]byte, 1764), 44100, 441},
+		{MediaSubsession{CodecName: "opus", RtpTimestampFrequency: 48000, Channels: 2}, []byte{0xfc, 0x01, 0x02}, 48000, 960},
+		{MediaSubsession{CodecName: "opus", RtpTimestampFrequency: 48000, Channels: 2}, []byte{0x0b, 0x83, 0x01}, 48000, 2880},
+	}
+	for _, test := range tests {
+		frames := parsingTestPackets(newRtpParser(test.media), newTestRtpPacket(true, 1, 0, test.payload))
+		if len(frames) != 1 {
+			t.Fatalf("%s: got %d frames, expected 1", test.media.CodecName, len(frames))
+		}
+		if frames[0].SampleRate != test.sampleRate || frames[0].Samples != test.samples {
+			t.Errorf("%s: timing %d/%d, expected %d/%d", test.media.CodecName,
+				frames[0].Samples, frames[0].SampleRate, test.samples, test.sampleRate)
+		}
+	}
+}
+
+func TestL16ByteOrder(t *testing.T) {
+	media := MediaSubsession{CodecName: "L16", RtpTimestampFrequency: 8000, Channels: 1}
+	packet := newTestRtpPacket(true, 1, 0, []byte{0x12, 0x34, 0xab, 0xcd})
+	frames := parsingTestPackets(newRtpParser(media), packet)
+	if len(frames) != 1 || !bytes.Equal(frames[0].Data, []byte{0x34, 0x12, 0xcd, 0xab}) {
+		t.Errorf("samples not converted to little-endian")
+	}
+	if !bytes.Equal(packet[RtpHeaderLen:], []byte{0x12, 0x34, 0xab, 0xcd}) {
+		t.Errorf("rtp packet changed %x", packet[RtpHeaderLen:])
+	}
+}
+
+func TestUnpackG726(t *testing.T) {
+	// 0x21: RFC 3551 puts the first code word in the low nibble
+	if codes := UnpackG726([]byte{0x21, 0x43}, 4, false); !bytes.Equal(codes, []byte{1, 2, 3, 4}) {
+		t.Errorf("unexpected rfc 3551 code words %v", codes)
+	}
+	if codes := UnpackG726([]byte{0x12, 0x34}, 4, true); !bytes.Equal(codes, []byte{1, 2, 3, 4}) {
+		t.Errorf("unexpected aal2 code words %v", codes)
+	}
+	// 3 bit code words 1..8 (mod 8) in aal2 packing: 001 010 011 100 101 110 111 000
+	if codes := UnpackG726([]byte{0x29, 0xcb, 0xb8}, 3, true); !bytes.Equal(codes, []byte{1, 2, 3, 4, 5, 6, 7, 0}) {
+		t.Errorf("unexpected 24 kbit/s code words %v", codes)
+	}
+}
@@ -0,0 +1,149 @@
+package rtspclient
+
+const (
+	av1ObuSequenceHeader    = 1
+	av1ObuTemporalDelimiter = 2
+	av1ObuTileList          = 8
+)
+
+// Av1RtpParser depacketizes AV1 (AV1 RTP payload format of the AOM). OBU
+// elements are reassembled and every temporal unit is delivered as a low
+// overhead bitstream (AV1 spec 5.2): a temporal delimiter followed by OBUs
+// that all carry obu_size.
+type Av1RtpParser struct {
+	assembler   frameAssembler
+	obuFragment []byte // OBU element continued in the next packet
+	synced      bool   // the last packet ended a temporal unit
+	isKeyFrame  bool
+}
+
+func (rtpParser *Av1RtpParser) SplitHeader(src []byte) (isCompletesFrame bool, header []byte, payload []byte) {
+	rtpData := src[RtpHeaderLen:]
+	seq := rtpSequence(src)
+	mark := 0 != src[1]&0x80
+	if len(rtpData) < 1 {
+		rtpParser.assembler.started = false
+		return false, src, src[len(src):]
+	}
+
+	aggregation := rtpData[0]
+	continuesFragment := 0 != aggregation&0x80 // Z
+	endsWithFragment := 0 != aggregation&0x40  // Y
+	elementCount := int(aggregation>>4) & 0x03 // W
+	newSequence := 0 != aggregation&0x08       // N
+
+	if rtpParser.assembler.started && rtpParser.assembler.nextSeq != seq {
+		// packet lost, wait for the next temporal unit
+		rtpParser.assembler.started = false
+		rtpParser.synced = false
+	}
+	if !rtpParser.assembler.started {
+		if continuesFragment || !(rtpParser.synced || newSequence) {
+			rtpParser.synced = mark
+			return mark, src, src[len(src):]
+		}
+		rtpParser.assembler.begin(seq)
+		rtpParser.assembler.frameBuf = append(rtpParser.assembler.frameBuf, av1ObuTemporalDelimiter<<3|0x02, 0x00)
+		rtpParser.obuFragment = rtpParser.obuFragment[:0]
+		rtpParser.isKeyFrame = false
+	}
+	if newSequence {
+		rtpParser.isKeyFrame = true
+	}
+
+	elements := rtpData[1:]
+	var obus []byte
+	for index := 0; 0 < len(elements); index++ {
+		var element []byte
+		if 0 == elementCount || index < elementCount-1 {
+			size, sizeLen := readLeb128(elements)
+			if 0 == sizeLen || len(elements) < sizeLen+size {
+				rtpParser.assembler.started = false
+				rtpParser.synced = mark
+				return mark, src, src[len(src):]
+			}
+			element = elements[sizeLen : sizeLen+size]
+			elements = elements[sizeLen+size:]
+		} else {
+			element = elements
+			elements = nil
+		}
+
+		if 0 == index && continuesFragment {
+			element = append(rtpParser.obuFragment, element...)
+			rtpParser.obuFragment = element[:0]
+		}
+		if 0 == len(elements) && endsWithFragment {
+			rtpParser.obuFragment = append(rtpParser.obuFragment[:0], element...)
+			break
+		}
+		obus = appendAv1Obu(obus, element)
+	}
+	rtpParser.assembler.push(seq, obus)
+
+	if !mark {
+		return false, src, src[len(src):]
+	}
+	rtpParser.synced = true
+	frame := rtpParser.assembler.finish()
+	return true, src[:RtpHeaderLen], frame
+}
+
+// appendAv1Obu appends an OBU with obu_has_size_field set, dropping the OBUs
+// the payload format forbids.
+func appendAv1Obu(dst []byte, obu []byte) []byte {
+	if len(obu) < 1 {
+		return dst
+	}
+	obuHeader := obu[0]
+	obuType := obuHeader >> 3 & 0x0f
+	if av1ObuTemporalDelimiter == obuType || av1ObuTileList == obuType {
+		return dst
+	}
+	if 0 != obuHeader&0x02 {
+		return append(dst, obu...)
+	}
+	headerLen := 1
+	if 0 != obuHeader&0x04 {
+		headerLen = 2
+	}
+	if len(obu) < headerLen {
+		return dst
+	}
+	dst = append(dst, obuHeader|0x02)
+	dst = append(dst, obu[1:headerLen]...)
+	dst = appendLeb128(dst, len(obu)-headerLen)
+	return append(dst, obu[headerLen:]...)
+}
+
+// readLeb128 returns a leb128 value and its size in bytes, 0 if malformed.
+func readLeb128(data []byte) (int, int) {
+	value := 0
+	for i := 0; i < 8 && i < len(data); i++ {
+		value |= int(data[i]&0x7f) << uint(7*i)
+		if 0 == data[i]&0x80 {
+			return value, i + 1
+		}
+	}
+	return 0, 0
+}
+
+func appendLeb128(dst []byte, value int) []byte {
+	for {
+		b := byte(value & 0x7f)
+		value >>= 7
+		if 0 == value {
+			return append(dst, b)
+		}
+		dst = append(dst, b|0x80)
+	}
+}
+
+func (rtpParser *Av1RtpParser) ParsingRtp(header []byte, payload []byte) (naluHeaderSize int, naluSize int) {
+	return 0, len(payload)
+}
+
+// FrameInfo marks temporal units starting a coded video sequence.
+func (rtpParser *Av1RtpParser) FrameInfo(data *RtspData) {
+	data.IsKeyFrame = rtpParser.isKeyFrame
+}
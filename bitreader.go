@@ -0,0 +1,123 @@
+package rtspclient
+
+import "errors"
+
+var errBitReaderEOF = errors.New("bit reader: not enough data")
+
+// bitReader reads big-endian bit fields from a byte slice.
+type bitReader struct {
+	data   []byte
+	offset int // in bits
+}
+
+func newBitReader(data []byte) *bitReader {
+	return &bitReader{data: data}
+}
+
+// bitsLeft returns the number of unread bits.
+func (reader *bitReader) bitsLeft() int {
+	return len(reader.data)*8 - reader.offset
+}
+
+// readBits reads n (at most 32) bits.
+func (reader *bitReader) readBits(n int) (uint32, error) {
+	if n < 0 || n > 32 || n > reader.bitsLeft() {
+		return 0, errBitReaderEOF
+	}
+	var value uint32
+	for i := 0; i < n; i++ {
+		bit := (reader.data[reader.offset>>3] >> (7 - uint(reader.offset&7))) & 0x01
+		value = (value << 1) | uint32(bit)
+		reader.offset++
+	}
+	return value, nil
+}
+
+// readFlag reads a single bit as a bool.
+func (reader *bitReader) readFlag() (bool, error) {
+	bit, err := reader.readBits(1)
+	return bit == 1, err
+}
+
+// skipBits advances n bits.
+func (reader *bitReader) skipBits(n int) error {
+	if n < 0 || n > reader.bitsLeft() {
+		return errBitReaderEOF
+	}
+	reader.offset += n
+	return nil
+}
+
+// readBytes reads n bytes, which need not be byte aligned.
+func (reader *bitReader) readBytes(n int) ([]byte, error) {
+	if n < 0 || n*8 > reader.bitsLeft() {
+		return nil, errBitReaderEOF
+	}
+	if 0 == reader.offset&7 {
+		start := reader.offset >> 3
+		reader.offset += n * 8
+		return reader.data[start : start+n], nil
+	}
+	dst := make([]byte, n)
+	for i := range dst {
+		value, _ := reader.readBits(8)
+		dst[i] = byte(value)
+	}
+	return dst, nil
+}
+
+// copyBits returns the bits between two offsets packed into bytes, the last
+// byte padded with zero bits.
+func (reader *bitReader) copyBits(start int, end int) []byte {
+	copyReader := &bitReader{data: reader.data, offset: start}
+	dst := make([]byte, (end-start+7)/8)
+	for i := range dst {
+		n := end - copyReader.offset
+		if n > 8 {
+			n = 8
+		}
+		value, _ := copyReader.readBits(n)
+		dst[i] = byte(value << uint(8-n))
+	}
+	return dst
+}
+
+// byteAlign skips to the next byte boundary.
+func (reader *bitReader) byteAlign() {
+	reader.offset = (reader.offset + 7) &^ 7
+}
+
+// readUE reads an unsigned exp-Golomb code ue(v).
+func (reader *bitReader) readUE() (uint32, error) {
+	leadingZeros := 0
+	for {
+		bit, err := reader.readBits(1)
+		if nil != err {
+			return 0, err
+		}
+		if 1 == bit {
+			break
+		}
+		leadingZeros++
+		if 31 < leadingZeros {
+			return 0, errors.New("bit reader: invalid exp-golomb code")
+		}
+	}
+	suffix, err := reader.readBits(leadingZeros)
+	if nil != err {
+		return 0, err
+	}
+	return (1<<uint(leadingZeros) - 1) + suffix, nil
+}
+
+// readSE reads a signed exp-Golomb code se(v).
+func (reader *bitReader) readSE() (int32, error) {
+	codeNum, err := reader.readUE()
+	if nil != err {
+		return 0, err
+	}
+	if 0 == codeNum&0x01 {
+		return -int32(codeNum >> 1), nil
+	}
+	return int32(codeNum>>1) + 1, nil
+}
@@ -0,0 +1,137 @@
+package rtspclient
+
+import (
+	"encoding/binary"
+	"strings"
+	"sync"
+)
+
+// RtpPacket is a parsed RTP packet. CSRCs, the header extension and the
+// padding are already removed.
+type RtpPacket struct {
+	Marker         bool
+	PayloadType    int
+	SequenceNumber uint16
+	Timestamp      uint32
+	SSRC           uint32
+	Payload        []byte
+	Raw            []byte // 12 byte fixed header followed by the payload
+}
+
+func newRtpPacket(src []byte) *RtpPacket {
+	return &RtpPacket{
+		Marker:         0 != src[1]&0x80,
+		PayloadType:    int(src[1] & 0x7f),
+		SequenceNumber: binary.BigEndian.Uint16(src[2:4]),
+		Timestamp:      binary.BigEndian.Uint32(src[4:8]),
+		SSRC:           binary.BigEndian.Uint32(src[8:12]),
+		Payload:        src[RtpHeaderLen:],
+		Raw:            src,
+	}
+}
+
+// IDepacketizer turns the packets of one track into frames. It keeps its
+// own state across packets. The packet memory is only valid during the call,
+// so frames must not point into it.
+type IDepacketizer interface {
+	Depacketize(packet *RtpPacket) []*RtspData
+}
+
+// DepacketizerFactory creates the depacketizer of a track.
+type DepacketizerFactory func(media MediaSubsession) IDepacketizer
+
+// CodecMatch selects the tracks a registered depacketizer is used for.
+type CodecMatch struct {
+	CodecName      string            // encoding name of the rtpmap, case insensitive
+	PayloadType    int               // only used if HasPayloadType
+	HasPayloadType bool              // match the payload type as well
+	Fmtp           map[string]string // fmtp parameters that must be equal, keys in lower case
+}
+
+func (match *CodecMatch) matches(media MediaSubsession) bool {
+	if !strings.EqualFold(match.CodecName, media.CodecName) {
+		return false
+	}
+	if match.HasPayloadType && match.PayloadType != media.PayloadFormat {
+		return false
+	}
+	for key, value := range match.Fmtp {
+		if mediaValue, ok := media.Fmtp[key]; !ok || !strings.EqualFold(mediaValue, value) {
+			return false
+		}
+	}
+	return true
+}
+
+func (match *CodecMatch) specificity() int {
+	specificity := len(match.Fmtp)
+	if match.HasPayloadType {
+		specificity += 0x100
+	}
+	return specificity
+}
+
+type depacketizerEntry struct {
+	match   CodecMatch
+	factory DepacketizerFactory
+}
+
+var (
+	depacketizerRegistry     []depacketizerEntry
+	depacketizerRegistryLock sync.RWMutex
+)
+
+// RegisterDepacketizer uses factory for all tracks with the encoding name,
+// in place of the built-in parser. Register codecs before Play.
+func RegisterDepacketizer(codecName string, factory DepacketizerFactory) {
+	RegisterDepacketizerMatch(CodecMatch{CodecName: codecName}, factory)
+}
+
+// RegisterDepacketizerMatch uses factory for the tracks selected by match.
+// A payload type match wins over fmtp matches, more fmtp parameters win over
+// fewer, and among equal matches the latest registration wins.
+func RegisterDepacketizerMatch(match CodecMatch, factory DepacketizerFactory) {
+	depacketizerRegistryLock.Lock()
+	defer depacketizerRegistryLock.Unlock()
+	depacketizerRegistry = append(depacketizerRegistry, depacketizerEntry{match: match, factory: factory})
+}
+
+func lookupDepacketizer(media MediaSubsession) DepacketizerFactory {
+	depacketizerRegistryLock.RLock()
+	defer depacketizerRegistryLock.RUnlock()
+	var found *depacketizerEntry
+	for index := range depacketizerRegistry {
+		entry := &depacketizerRegistry[index]
+		if !entry.match.matches(media) {
+			continue
+		}
+		if nil == found || entry.match.specificity() >= found.match.specificity() {
+			found = entry
+		}
+	}
+	if nil == found {
+		return nil
+	}
+	return found.factory
+}
+
+// NewParserDepacketizer adapts a parser of the split/parse contract, so it
+// can be registered as a depacketizer.
+func NewParserDepacketizer(parser IRtpParseInterface) IDepacketizer {
+	return &parserDepacketizer{rtpParser: &RtpParser{
+		maxPayloadLength: MaxPayloadLength,
+		rtpSourceHandler: parser,
+	}}
+}
+
+type parserDepacketizer struct {
+	rtpParser *RtpParser
+}
+
+func (depacketizer *parserDepacketizer) Depacketize(packet *RtpPacket) []*RtspData {
+	var frames []*RtspData
+	depacketizer.rtpParser.parsingFrames(packet.Raw, func(data *RtspData) {
+		frames = append(frames, data)
+	})
+	return frames
+}
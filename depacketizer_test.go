@@ -0,0 +1,53 @@
+package rtspclient
+
+import (
+	"testing"
+)
+
+type testDepacketizer struct {
+	name    string
+	packets int
+}
+
+func (depacketizer *testDepacketizer) Depacketize(packet *RtpPacket) []*RtspData {
+	depacketizer.packets++
+	if !packet.Marker {
+		return nil
+	}
+	data := append([]byte(depacketizer.name), packet.Payload...)
+	return []*RtspData{{Timestamp: packet.Timestamp, Data: data}}
+}
+
+func registerTestDepacketizer(match CodecMatch, name string) {
+	RegisterDepacketizerMatch(match, func(media MediaSubsession) IDepacketizer {
+		return &testDepacketizer{name: name}
+	})
+}
+
+func TestRegisterDepacketizer(t *testing.T) {
+	registerTestDepacketizer(CodecMatch{CodecName: "x-inhouse"}, "name")
+	registerTestDepacketizer(CodecMatch{CodecName: "x-inhouse", Fmtp: map[string]string{"mode": "b"}}, "fmtp")
+	registerTestDepacketizer(CodecMatch{CodecName: "x-inhouse", PayloadType: 100, HasPayloadType: true}, "pt")
+
+	tests := []struct {
+		media    MediaSubsession
+		expected string
+	}{
+		{MediaSubsession{CodecName: "X-INHOUSE", PayloadFormat: 99}, "name"},
+		{MediaSubsession{CodecName: "x-inhouse", PayloadFormat: 99, Fmtp: map[string]string{"mode": "B"}}, "fmtp"},
+		{MediaSubsession{CodecName: "x-inhouse", PayloadFormat: 100, Fmtp: map[string]string{"mode": "b"}}, "pt"},
+	}
+	for _, test := range tests {
+		rtpParser := newRtpParser(test.media)
+		frames := parsingTestPackets(rtpParser,
+			newTestRtpPacket(false, 1, 10, []byte{'1'}),
+			newTestRtpPacket(true, 2, 10, []byte{'2'}))
+		if len(frames) != 1 || string(frames[0].Data) != test.expected+"2" || frames[0].Timestamp != 10 {
+			t.Errorf("%+v: got %v", test.media, frames)
+		}
+	}
+
+	if nil != lookupDepacketizer(MediaSubsession{CodecName: "H264"}) {
+		t.Error("built-in codec replaced")
+	}
+}
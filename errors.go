@@ -0,0 +1,82 @@
+package rtspclient
+
+import (
+	"errors"
+	"net"
+	"strconv"
+)
+
+// Errors of a session, test for them with errors.Is.
+var (
+	ErrAuthFailed           = errors.New("rtsp: authentication failed")
+	ErrTimeout              = errors.New("rtsp: timeout")
+	ErrUnsupportedTransport = errors.New("rtsp: unsupported transport")
+	ErrSDPInvalid           = errors.New("rtsp: invalid sdp")
+	ErrInvalidURL           = errors.New("rtsp: invalid url")
+	ErrConnectFailed        = errors.New("rtsp: connect failed")
+	ErrDisconnected         = errors.New("rtsp: connection disconnected")
+	ErrInvalidResponse      = errors.New("rtsp: invalid response")
+	ErrRequestPending       = errors.New("rtsp: waiting for the reply of the last request")
+	ErrSessionConnected     = errors.New("rtsp: session is connected")
+	ErrSessionClosed        = errors.New("rtsp: session closed")
+	ErrInvalidState         = errors.New("rtsp: invalid session state")
+	ErrNoTrack              = errors.New("rtsp: no track on the rtp channel")
+	ErrNoRtpReceived        = errors.New("rtsp: no rtp received on the channel yet")
+)
+
+// StatusError is a response with an error status code. It matches
+// ErrAuthFailed for 401 and ErrUnsupportedTransport for 461.
+type StatusError struct {
+	Method string
+	Code   int
+}
+
+func (err *StatusError) Error() string {
+	return "rtsp: " + err.Method + " response error: " + strconv.Itoa(err.Code)
+}
+
+// Is reports whether the status code is the one of target.
+func (err *StatusError) Is(target error) bool {
+	switch target {
+	case ErrAuthFailed:
+		return 401 == err.Code
+	case ErrUnsupportedTransport:
+		return 461 == err.Code
+	}
+	return false
+}
+
+// ConnectError is a failure to reach the server. It matches ErrConnectFailed,
+// and ErrTimeout if the dial timed out.
+type ConnectError struct {
+	Address string
+	Err     error
+}
+
+func (err *ConnectError) Error() string {
+	return "rtsp: connect " + err.Address + ": " + err.Err.Error()
+}
+
+func (err *ConnectError) Unwrap() error {
+	return err.Err
+}
+
+// Is reports whether target is ErrConnectFailed or a timeout.
+func (err *ConnectError) Is(target error) bool {
+	switch target {
+	case ErrConnectFailed:
+		return true
+	case ErrTimeout:
+		var netErr net.Error
+		return errors.As(err.Err, &netErr) && netErr.Timeout()
+	}
+	return false
+}
+
+// checkResponse returns the error of a response to method.
+func checkResponse(method string, response *RtspResponseContext) error {
+	if 200 != response.Status {
+		return &StatusError{Method: method, Code: response.Status}
+	}
+	return nil
+}
@@ -0,0 +1,68 @@
+package rtspclient
+
+// frameAssembler collects the payloads of one frame from consecutive RTP
+// packets and drops the frame if a packet in between is missing.
+type frameAssembler struct {
+	frameBuf []byte
+	started  bool
+	nextSeq  uint16
+	midFrame bool // used by pushMarked: the last packet did not end a frame
+
+	maxFrameSize int // 0 for MaxPayloadLength
+}
+
+// begin starts a new frame at packet seq, discarding any partial one.
+func (assembler *frameAssembler) begin(seq uint16) {
+	assembler.frameBuf = assembler.frameBuf[:0]
+	assembler.started = true
+	assembler.nextSeq = seq
+}
+
+// push appends the payload of packet seq. It returns false and drops the
+// frame if no frame is in progress or a packet was lost.
+func (assembler *frameAssembler) push(seq uint16, data []byte) bool {
+	maxFrameSize := MaxPayloadLength
+	if 0 < assembler.maxFrameSize && assembler.maxFrameSize < maxFrameSize {
+		maxFrameSize = assembler.maxFrameSize
+	}
+	if !assembler.started || seq != assembler.nextSeq ||
+		len(assembler.frameBuf)+len(data) > maxFrameSize {
+		assembler.started = false
+		return false
+	}
+	assembler.frameBuf = append(assembler.frameBuf, data...)
+	assembler.nextSeq = seq + 1
+	return true
+}
+
+// finish ends the frame and returns it, or nil if it is incomplete. The
+// frame is only valid until the next begin.
+func (assembler *frameAssembler) finish() []byte {
+	if !assembler.started {
+		return nil
+	}
+	assembler.started = false
+	return assembler.frameBuf
+}
+
+// pushMarked appends the payload of an RTP packet to a frame that ends at the
+// marker bit and starts with the next packet. It returns the frame once the
+// marker bit is seen, or nil.
+func (assembler *frameAssembler) pushMarked(src []byte) []byte {
+	seq := rtpSequence(src)
+	if !assembler.midFrame {
+		assembler.begin(seq)
+	}
+	assembler.push(seq, src[RtpHeaderLen:])
+
+	assembler.midFrame = 0 == src[1]&0x80
+	if assembler.midFrame {
+		return nil
+	}
+	return assembler.finish()
+}
+
+// rtpSequence returns the sequence number of an RTP packet.
+func rtpSequence(src []byte) uint16 {
+	return uint16(src[2])<<8 | uint16(src[3])
+}
@@ -0,0 +1,72 @@
+package rtspclient
+
+import (
+	"math/bits"
+	"sync"
+)
+
+const (
+	minFramePoolBits = 10 // 1 KiB
+	maxFramePoolBits = 23 // MaxPayloadLength
+)
+
+// framePools holds frame buffers by power of two size classes.
+var framePools [maxFramePoolBits + 1]sync.Pool
+
+var rtspDataPool = sync.Pool{
+	New: func() interface{} {
+		return &RtspData{}
+	},
+}
+
+func framePoolClass(size int) int {
+	class := bits.Len(uint(size - 1))
+	if class < minFramePoolBits {
+		class = minFramePoolBits
+	}
+	return class
+}
+
+// getFrameBuffer returns a buffer of length size, from the pool if size is
+// not above MaxPayloadLength.
+func getFrameBuffer(size int) *[]byte {
+	class := framePoolClass(size)
+	if maxFramePoolBits < class {
+		buffer := make([]byte, size)
+		return &buffer
+	}
+	if pooled, ok := framePools[class].Get().(*[]byte); ok {
+		*pooled = (*pooled)[:size]
+		return pooled
+	}
+	buffer := make([]byte, size, 1<<uint(class))
+	return &buffer
+}
+
+func putFrameBuffer(buffer *[]byte) {
+	class := framePoolClass(cap(*buffer))
+	if maxFramePoolBits < class || cap(*buffer) != 1<<uint(class) {
+		return
+	}
+	framePools[class].Put(buffer)
+}
+
+// newPooledRtspData returns a frame with a pooled copy of data.
+func newPooledRtspData(data []byte) *RtspData {
+	rtspData := rtspDataPool.Get().(*RtspData)
+	rtspData.buffer = getFrameBuffer(len(data))
+	rtspData.Data = *rtspData.buffer
+	copy(rtspData.Data, data)
+	return rtspData
+}
+
+// Release hands the frame and its data back to the library for reuse. It is
+// optional; call it at most once, when neither the frame nor its Data is used
+// anymore.
+func (data *RtspData) Release() {
+	if nil != data.buffer {
+		putFrameBuffer(data.buffer)
+	}
+	*data = RtspData{}
+	rtspDataPool.Put(data)
+}
@@ -0,0 +1,52 @@
+package rtspclient
+
+import (
+	"bytes"
+	"testing"
+)
+
+func newTestFillPacket(seq uint16, fill byte) []byte {
+	payload := bytes.Repeat([]byte{fill}, 1500)
+	payload[0] = 0x65
+	return newTestRtpPacket(true, seq, uint32(seq)*3000, payload)
+}
+
+func TestFramePoolKeptFrame(t *testing.T) {
+	rtpParser := newRtpParser(MediaSubsession{CodecName: "H264", RtpTimestampFrequency: 90000})
+	kept := parsingTestPackets(rtpParser, newTestFillPacket(0, 0x11))
+	if 1 != len(kept) {
+		t.Fatalf("%d frames", len(kept))
+	}
+	expected := append([]byte(nil), kept[0].Data...)
+
+	// later frames, released or not, leave the kept one alone
+	for seq := uint16(1); seq < 8; seq++ {
+		frames := parsingTestPackets(rtpParser, newTestFillPacket(seq, byte(seq)))
+		if 1 != len(frames) || byte(seq) != frames[0].Data[1] {
+			t.Fatalf("frame %d corrupted", seq)
+		}
+		if 0 == seq%2 {
+			frames[0].Release()
+		}
+		if !bytes.Equal(expected, kept[0].Data) {
+			t.Fatalf("kept frame changed by frame %d", seq)
+		}
+	}
+}
+
+func TestFramePoolRelease(t *testing.T) {
+	rtpParser := newRtpParser(MediaSubsession{CodecName: "H264", RtpTimestampFrequency: 90000})
+	frames := parsingTestPackets(rtpParser, newTestFillPacket(0, 0x11))
+	released := frames[0]
+	released.Release()
+	if nil != released.Data || nil != released.buffer || nil != released.Session {
+		t.Errorf("released frame still refers to its data")
+	}
+
+	// a buffer handed back is reused for one frame at a time only
+	first := parsingTestPackets(rtpParser, newTestFillPacket(1, 0x22))[0]
+	second := parsingTestPackets(rtpParser, newTestFillPacket(2, 0x33))[0]
+	if &first.Data[0] == &second.Data[0] || 0x22 != first.Data[1] || 0x33 != second.Data[1] {
+		t.Errorf("buffer shared by two frames")
+	}
+}
@@ -0,0 +1,65 @@
+package rtspclient
+
+// G711RtpParser handles PCMU and PCMA (RFC 3551), one frame per packet with
+// one byte per sample.
+type G711RtpParser struct {
+	SimpleRtpParser
+	sampleRate int
+	channels   int
+}
+
+func newG711RtpParser(media MediaSubsession) *G711RtpParser {
+	sampleRate := media.RtpTimestampFrequency
+	if sampleRate <= 0 {
+		sampleRate = 8000
+	}
+	return &G711RtpParser{
+		sampleRate: sampleRate,
+		channels:   media.channelCount(),
+	}
+}
+
+// FrameInfo reports the sample timing of the frame.
+func (rtpParser *G711RtpParser) FrameInfo(data *RtspData) {
+	data.SampleRate = rtpParser.sampleRate
+	data.Samples = len(data.Data) / rtpParser.channels
+}
+
+// DecodePCMU expands G.711 mu-law samples to 16 bit linear PCM.
+func DecodePCMU(src []byte) []int16 {
+	dst := make([]int16, len(src))
+	for i, value := range src {
+		value = ^value
+		sample := ((int16(value&0x0f) << 3) + 0x84) << ((value & 0x70) >> 4)
+		if 0 != value&0x80 {
+			dst[i] = 0x84 - sample
+		} else {
+			dst[i] = sample - 0x84
+		}
+	}
+	return dst
+}
+
+// DecodePCMA expands G.711 A-law samples to 16 bit linear PCM.
+func DecodePCMA(src []byte) []int16 {
+	dst := make([]int16, len(src))
+	for i, value := range src {
+		value ^= 0x55
+		sample := int16(value&0x0f) << 4
+		segment := (value & 0x70) >> 4
+		switch segment {
+		case 0:
+			sample += 8
+		case 1:
+			sample += 0x108
+		default:
+			sample = (sample + 0x108) << (segment - 1)
+		}
+		if 0 != value&0x80 {
+			dst[i] = sample
+		} else {
+			dst[i] = -sample
+		}
+	}
+	return dst
+}
@@ -0,0 +1,83 @@
+package rtspclient
+
+import "strings"
+
+// G726RtpParser handles G726-16/24/32/40 (RFC 3551) and the AAL2-G726
+// variants, one frame per packet. The payload is delivered unchanged, use
+// UnpackG726 to get one code word per sample.
+type G726RtpParser struct {
+	SimpleRtpParser
+	bitsPerSample int
+	sampleRate    int
+	aal2          bool
+}
+
+func newG726RtpParser(media MediaSubsession) *G726RtpParser {
+	codecName := strings.ToUpper(media.CodecName)
+	rtpParser := &G726RtpParser{
+		bitsPerSample: 4,
+		sampleRate:    media.RtpTimestampFrequency,
+		aal2:          strings.HasPrefix(codecName, "AAL2-"),
+	}
+	if rtpParser.sampleRate <= 0 {
+		rtpParser.sampleRate = 8000
+	}
+	switch {
+	case strings.HasSuffix(codecName, "-16"):
+		rtpParser.bitsPerSample = 2
+	case strings.HasSuffix(codecName, "-24"):
+		rtpParser.bitsPerSample = 3
+	case strings.HasSuffix(codecName, "-40"):
+		rtpParser.bitsPerSample = 5
+	}
+	return rtpParser
+}
+
+// BitsPerSample returns the code word size, 2 to 5 bits.
+func (rtpParser *G726RtpParser) BitsPerSample() int {
+	return rtpParser.bitsPerSample
+}
+
+// IsAAL2 reports whether the payload uses the ITU-T I.366.2 (big-endian)
+// packing instead of the RFC 3551 (little-endian) one.
+func (rtpParser *G726RtpParser) IsAAL2() bool {
+	return rtpParser.aal2
+}
+
+// FrameInfo reports the sample timing of the frame.
+func (rtpParser *G726RtpParser) FrameInfo(data *RtspData) {
+	data.SampleRate = rtpParser.sampleRate
+	data.Samples = len(data.Data) * 8 / rtpParser.bitsPerSample
+}
+
+// UnpackG726 splits a G.726 payload into one code word per byte. RFC 3551
+// packs the first sample into the least significant bits of the first octet,
+// AAL2 packing starts with the most significant bits.
+func UnpackG726(src []byte, bitsPerSample int, aal2 bool) []byte {
+	if bitsPerSample < 2 || bitsPerSample > 5 {
+		return nil
+	}
+	dst := make([]byte, 0, len(src)*8/bitsPerSample)
+	mask := uint32(1)<<uint(bitsPerSample) - 1
+	var bits uint32
+	bitCount := 0
+	for _, value := range src {
+		if aal2 {
+			bits = bits<<8 | uint32(value)
+			bitCount += 8
+			for bitCount >= bitsPerSample {
+				bitCount -= bitsPerSample
+				dst = append(dst, byte((bits>>uint(bitCount))&mask))
+			}
+		} else {
+			bits |= uint32(value) << uint(bitCount)
+			bitCount += 8
+			for bitCount >= bitsPerSample {
+				dst = append(dst, byte(bits&mask))
+				bits >>= uint(bitsPerSample)
+				bitCount -= bitsPerSample
+			}
+		}
+	}
+	return dst
+}
@@ -0,0 +1,91 @@
+package rtspclient
+
+// H263RtpParser depacketizes H.263+ and H.263++ (H263-1998, H263-2000,
+// RFC 4629). Pictures are joined up to the marker bit with the start code
+// bytes the payload header stands for restored.
+type H263RtpParser struct {
+	assembler  frameAssembler
+	isKeyFrame bool
+	startBuf   []byte
+}
+
+func (rtpParser *H263RtpParser) SplitHeader(src []byte) (isCompletesFrame bool, header []byte, payload []byte) {
+	rtpData := src[RtpHeaderLen:]
+	seq := rtpSequence(src)
+	if len(rtpData) < 2 {
+		rtpParser.assembler.started = false
+		return false, src, src[len(src):]
+	}
+
+	// RR(5) P(1) V(1) PLEN(6) PEBIT(3)
+	startBit := 0 != rtpData[0]&0x04
+	headerLen := 2 + (int(rtpData[0]&0x01)<<5 | int(rtpData[1]>>3))
+	if 0 != rtpData[0]&0x02 {
+		// VRC byte
+		headerLen++
+	}
+	if len(rtpData) < headerLen {
+		rtpParser.assembler.started = false
+		return false, src, src[len(src):]
+	}
+	data := rtpData[headerLen:]
+
+	if startBit {
+		// picture start codes begin with 22 bits 0000 0000 0000 0000 1000 00
+		if 1 <= len(data) && 0x80 == data[0]&0xfc {
+			rtpParser.assembler.begin(seq)
+			rtpParser.isKeyFrame = h263IsIntra(data)
+		}
+		rtpParser.startBuf = append(append(rtpParser.startBuf[:0], 0x00, 0x00), data...)
+		data = rtpParser.startBuf
+	}
+	rtpParser.assembler.push(seq, data)
+
+	if 0 == src[1]&0x80 {
+		return false, src, src[len(src):]
+	}
+	frame := rtpParser.assembler.finish()
+	return true, src[:RtpHeaderLen], frame
+}
+
+// h263IsIntra reports whether a picture header, without its two leading zero
+// bytes, describes an INTRA or EI picture.
+func h263IsIntra(data []byte) bool {
+	reader := newBitReader(data)
+	// rest of PSC, TR, the two first PTYPE bits, split screen, document
+	// camera and freeze picture release
+	if err := reader.skipBits(6 + 8 + 5); nil != err {
+		return false
+	}
+	sourceFormat, err := reader.readBits(3)
+	if nil != err {
+		return false
+	}
+	if 7 != sourceFormat {
+		pictureCodingType, err := reader.readBits(1)
+		return nil == err && 0 == pictureCodingType
+	}
+
+	// PLUSPTYPE
+	ufep, err := reader.readBits(3)
+	if nil != err {
+		return false
+	}
+	if 1 == ufep {
+		// OPPTYPE
+		if err := reader.skipBits(18); nil != err {
+			return false
+		}
+	}
+	pictureType, err := reader.readBits(3)
+	return nil == err && (0 == pictureType || 4 == pictureType)
+}
+
+func (rtpParser *H263RtpParser) ParsingRtp(header []byte, payload []byte) (naluHeaderSize int, naluSize int) {
+	return 0, len(payload)
+}
+
+// FrameInfo marks INTRA pictures.
+func (rtpParser *H263RtpParser) FrameInfo(data *RtspData) {
+	data.IsKeyFrame = rtpParser.isKeyFrame
+}
@@ -1,6 +1,15 @@
 package rtspclient
 
 type H264RtpParser struct {
+	parameterSets parameterSetTracker
+}
+
+func newH264RtpParser(media MediaSubsession) *H264RtpParser {
+	rtpParser := &H264RtpParser{}
+	rtpParser.parameterSets.videoInfoChanged = true
+	rtpParser.parameterSets.spsInfoStale = true
+	rtpParser.parameterSets.sets = *ParseH264ParameterSets(media.Fmtp)
+	return rtpParser
 }
 
 func (rtpParser *H264RtpParser) SplitHeader(src []byte) (isCompletesFrame bool, header []byte, payload []byte) {
@@ -59,37 +68,64 @@ func (rtpParser *H264RtpParser) SplitHeader(src []byte) (isCompletesFrame bool,
 	return isCompletesFrame, src[:skipHeaderLen+RtpHeaderLen], src[skipHeaderLen+RtpHeaderLen:]
 }
 func (rtpParser *H264RtpParser) ParsingRtp(header []byte, payload []byte) (naluHeaderSize int, naluSize int) {
-	if len(header) < RtpHeaderLen+2 {
+	if len(header) < RtpHeaderLen+1 {
 		return 0, len(payload)
 	}
 
 	naluHeader := header[RtpHeaderLen:]
-	packetNALUnitType := (naluHeader[0] & 0x7E) >> 1
+	packetNALUnitType := naluHeader[0] & 0x1f
 
 	switch packetNALUnitType {
 	case 24, 25: // STAP-A STAP-B
 		{
-			naluSize = int((payload[0] << 8) | naluHeader[1])
 			naluHeaderSize = 2
 		}
 		break
 	case 26: // MTAP16
 		{
-			naluSize = int((naluHeader[3] << 8) | naluHeader[4])
+			// size, DOND and a 16 bit TS offset
 			naluHeaderSize = 5
 		}
 		break
 	case 27: // MTAP24
 		{
-			naluSize = int((naluHeader[3] << 8) | naluHeader[4])
+			// size, DOND and a 24 bit TS offset
 			naluHeaderSize = 6
 		}
 		break
 	default:
 		{
-			naluSize = len(payload)
-			naluHeaderSize = 0
+			return 0, len(payload)
 		}
 	}
-	return naluHeaderSize, naluSize
+	if len(payload) < naluHeaderSize {
+		return 0, len(payload)
+	}
+	naluSize = int(payload[0])<<8 | int(payload[1])
+	return naluHeaderSize, naluSize + naluHeaderSize
+}
+
+// FrameInfo follows in-band parameter sets, marks IDR slices and decodes SEI.
+func (rtpParser *H264RtpParser) FrameInfo(data *RtspData) {
+	data.IsKeyFrame = rtpParser.parameterSets.update(data.Data, data.Timestamp)
+	if 0 < len(data.Data) && h264NalSEI == data.Data[0]&0x1f {
+		data.SEI, _ = ParseH264SEI(data.Data, rtpParser.parameterSets.activeSPS())
+	}
+}
+
+// ParameterSets returns the current SPS and PPS. Call it from the data
+// handler only.
+func (rtpParser *H264RtpParser) ParameterSets() *ParameterSets {
+	return &rtpParser.parameterSets.sets
+}
+
+func (rtpParser *H264RtpParser) updateVideoInfo(media *MediaSubsession) bool {
+	return rtpParser.parameterSets.updateVideoInfo(media)
+}
+
+func (rtpParser *H264RtpParser) parameterSetsPrefix(data *RtspData) [][]byte {
+	if !data.IsKeyFrame {
+		return nil
+	}
+	return rtpParser.parameterSets.prefix(data.Timestamp)
 }
@@ -1,6 +1,16 @@
 package rtspclient
 
 type HevcRtpParser struct {
+	parameterSets parameterSetTracker
+}
+
+func newHevcRtpParser(media MediaSubsession) *HevcRtpParser {
+	rtpParser := &HevcRtpParser{}
+	rtpParser.parameterSets.hevc = true
+	rtpParser.parameterSets.videoInfoChanged = true
+	rtpParser.parameterSets.spsInfoStale = true
+	rtpParser.parameterSets.sets = *ParseH265ParameterSets(media.Fmtp)
+	return rtpParser
 }
 
 func (rtpParser *HevcRtpParser) SplitHeader(src []byte) (isCompletesFrame bool, header []byte, payload []byte) {
@@ -65,8 +75,14 @@ func (rtpParser *HevcRtpParser) ParsingRtp(header []byte, payload []byte) (naluH
 	case 48: // Aggregation Packet (AP)
 		{
 			// We skip over the 2-byte Payload Header, and the DONL header (if any).
-			naluSize = int((payload[0] << 8) | payload[1])
+			if len(payload) < 2 {
+				return 0, len(payload)
+			}
+			naluSize = int(payload[0])<<8 | int(payload[1])
 			naluHeaderSize = 2
+			if len(payload)-naluHeaderSize < naluSize {
+				naluSize = len(payload) - naluHeaderSize
+			}
 		}
 		break
 	default:
@@ -77,3 +93,30 @@ func (rtpParser *HevcRtpParser) ParsingRtp(header []byte, payload []byte) (naluH
 	}
 	return naluHeaderSize, naluSize + naluHeaderSize
 }
+
+// FrameInfo follows in-band parameter sets, marks IRAP slices and decodes SEI.
+func (rtpParser *HevcRtpParser) FrameInfo(data *RtspData) {
+	data.IsKeyFrame = rtpParser.parameterSets.update(data.Data, data.Timestamp)
+	if 0 < len(data.Data) {
+		if nalType := int(data.Data[0]>>1) & 0x3f; hevcNalPrefixSEI == nalType || hevcNalSuffixSEI == nalType {
+			data.SEI, _ = ParseH265SEI(data.Data, rtpParser.parameterSets.activeSPS())
+		}
+	}
+}
+
+// ParameterSets returns the current VPS, SPS and PPS. Call it from the data
+// handler only.
+func (rtpParser *HevcRtpParser) ParameterSets() *ParameterSets {
+	return &rtpParser.parameterSets.sets
+}
+
+func (rtpParser *HevcRtpParser) updateVideoInfo(media *MediaSubsession) bool {
+	return rtpParser.parameterSets.updateVideoInfo(media)
+}
+
+func (rtpParser *HevcRtpParser) parameterSetsPrefix(data *RtspData) [][]byte {
+	if !data.IsKeyFrame {
+		return nil
+	}
+	return rtpParser.parameterSets.prefix(data.Timestamp)
+}
@@ -0,0 +1,26 @@
+package rtspclient
+
+import (
+	"bytes"
+	"testing"
+)
+
+func TestHevcAggregationPacketTruncated(t *testing.T) {
+	header := append(newTestRtpPacket(true, 1, 0, nil), 0x60, 0x01)
+	var rtpParser HevcRtpParser
+	// the tail of an aggregation packet cut after the first byte of a size
+	if naluHeaderSize, naluSize := rtpParser.ParsingRtp(header, []byte{0x00}); 0 != naluHeaderSize || 1 != naluSize {
+		t.Errorf("truncated size: %d %d", naluHeaderSize, naluSize)
+	}
+	// a size running past the packet
+	if naluHeaderSize, naluSize := rtpParser.ParsingRtp(header, []byte{0x00, 0x10, 0x26, 0x01, 0xaf}); 2 != naluHeaderSize || 5 != naluSize {
+		t.Errorf("oversized nal unit: %d %d", naluHeaderSize, naluSize)
+	}
+
+	// an IDR slice, then a size cut after its first byte
+	ap := []byte{0x60, 0x01, 0x00, 0x03, 0x26, 0x01, 0xaf, 0x00}
+	frames := parsingTestPackets(newRtpParser(MediaSubsession{CodecName: "H265", RtpTimestampFrequency: 90000}), newTestRtpPacket(true, 1, 0, ap))
+	if len(frames) != 1 || !bytes.HasPrefix(frames[0].Data, []byte{0x26, 0x01, 0xaf}) {
+		t.Errorf("truncated aggregation packet: %v", frames)
+	}
+}
@@ -0,0 +1,212 @@
+package rtspclient
+
+import (
+	"encoding/binary"
+)
+
+const (
+	jpegHeaderLen        = 8
+	jpegRestartHeaderLen = 4
+	jpegQTableHeaderLen  = 4
+)
+
+// JpegRtpParser depacketizes Motion JPEG (RFC 2435). Fragments are joined by
+// their fragment offset and every frame is delivered as a complete JFIF image
+// with regenerated quantization, Huffman and restart headers.
+type JpegRtpParser struct {
+	frameBuf   []byte // headers and scan data of the frame being reassembled
+	scanLen    int    // scan data received so far
+	frameValid bool   // first fragment seen and no fragment missing
+	qTables    map[int][]byte
+}
+
+func newJpegRtpParser() *JpegRtpParser {
+	return &JpegRtpParser{
+		qTables: make(map[int][]byte),
+	}
+}
+
+func (rtpParser *JpegRtpParser) SplitHeader(src []byte) (isCompletesFrame bool, header []byte, payload []byte) {
+	rtpData := src[RtpHeaderLen:]
+	if len(rtpData) < jpegHeaderLen {
+		rtpParser.frameValid = false
+		return false, src, src[len(src):]
+	}
+	mark := (src[1] & 0x80) != 0
+
+	fragmentOffset := int(rtpData[1])<<16 | int(rtpData[2])<<8 | int(rtpData[3])
+	jpegType := int(rtpData[4])
+	q := int(rtpData[5])
+	width := int(rtpData[6]) * 8
+	height := int(rtpData[7]) * 8
+	pos := jpegHeaderLen
+
+	restartInterval := 0
+	if 64 <= jpegType && jpegType <= 127 {
+		if len(rtpData) < pos+jpegRestartHeaderLen {
+			rtpParser.frameValid = false
+			return false, src, src[len(src):]
+		}
+		restartInterval = int(binary.BigEndian.Uint16(rtpData[pos:]))
+		pos += jpegRestartHeaderLen
+	}
+
+	if 0 == fragmentOffset {
+		rtpParser.frameValid = false
+		var qTables []byte
+		if 128 <= q {
+			if len(rtpData) < pos+jpegQTableHeaderLen {
+				return false, src, src[len(src):]
+			}
+			precision := rtpData[pos+1]
+			length := int(binary.BigEndian.Uint16(rtpData[pos+2:]))
+			pos += jpegQTableHeaderLen
+			if len(rtpData) < pos+length {
+				return false, src, src[len(src):]
+			}
+			if 0 < length {
+				qTables = rtpParser.convertQTables(rtpData[pos:pos+length], precision)
+				if 255 != q {
+					// Q 128-254 tables may be left out of later frames
+					rtpParser.qTables[q] = qTables
+				}
+			} else {
+				qTables = rtpParser.qTables[q]
+			}
+			pos += length
+		} else if q < 100 {
+			qTables = makeJpegTables(q)
+		}
+		if nil == qTables {
+			// reserved Q or tables never received
+			return false, src, src[len(src):]
+		}
+
+		rtpParser.frameBuf = makeJpegHeaders(rtpParser.frameBuf[:0], jpegType&0x3f, width, height, qTables, restartInterval)
+		rtpParser.scanLen = 0
+		rtpParser.frameValid = true
+	} else if !rtpParser.frameValid || fragmentOffset != rtpParser.scanLen {
+		// missed a fragment, drop the frame
+		rtpParser.frameValid = false
+		return false, src, src[len(src):]
+	}
+
+	if len(rtpParser.frameBuf)+len(rtpData)-pos > MaxPayloadLength {
+		rtpParser.frameValid = false
+		return false, src, src[len(src):]
+	}
+	rtpParser.frameBuf = append(rtpParser.frameBuf, rtpData[pos:]...)
+	rtpParser.scanLen += len(rtpData) - pos
+
+	if !mark {
+		return false, src, src[len(src):]
+	}
+	if !rtpParser.frameValid {
+		return true, src, src[len(src):]
+	}
+	rtpParser.frameValid = false
+	frameLen := len(rtpParser.frameBuf)
+	if frameLen < 2 || 0xff != rtpParser.frameBuf[frameLen-2] || 0xd9 != rtpParser.frameBuf[frameLen-1] {
+		// EOI
+		rtpParser.frameBuf = append(rtpParser.frameBuf, 0xff, 0xd9)
+	}
+	return true, src[:RtpHeaderLen], rtpParser.frameBuf
+}
+
+// convertQTables returns the luma and chroma tables of a quantization table
+// header as DQT segment bodies, precision byte included.
+func (rtpParser *JpegRtpParser) convertQTables(data []byte, precision byte) []byte {
+	// one Pq/Tq byte and 64 or 128 values per table
+	tables := make([]byte, 0, 2*129)
+	for id := 0; id < 2; id++ {
+		tableLen := 64
+		if 0 != precision&(1<<uint(id)) {
+			tableLen = 128
+		}
+		if len(data) < tableLen {
+			if 1 == id && 0 < len(tables) {
+				// a single table, use it for chroma as well
+				luma := tables
+				tables = append(tables, luma[0]|0x01)
+				return append(tables, luma[1:]...)
+			}
+			return nil
+		}
+		pqTq := byte(id)
+		if 128 == tableLen {
+			pqTq |= 0x10
+		}
+		tables = append(tables, pqTq)
+		tables = append(tables, data[:tableLen]...)
+		data = data[tableLen:]
+	}
+	return tables
+}
+
+func (rtpParser *JpegRtpParser) ParsingRtp(header []byte, payload []byte) (naluHeaderSize int, naluSize int) {
+	return 0, len(payload)
+}
+
+// FrameInfo marks every frame as a key frame.
+func (rtpParser *JpegRtpParser) FrameInfo(data *RtspData) {
+	data.IsKeyFrame = true
+}
+
+// makeJpegHeaders appends the JFIF headers of a frame to dst, as
+// MakeHeaders() of RFC 2435 Appendix B. qTables is either 128 bytes of 8 bit
+// tables in zigzag order or DQT bodies from convertQTables.
+func makeJpegHeaders(dst []byte, jpegType int, width int, height int, qTables []byte, restartInterval int) []byte {
+	// SOI
+	dst = append(dst, 0xff, 0xd8)
+	// APP0 JFIF 1.1, no thumbnail
+	dst = append(dst, 0xff, 0xe0, 0x00, 0x10, 'J', 'F', 'I', 'F', 0x00, 0x01, 0x01, 0x00, 0x00, 0x01, 0x00, 0x01, 0x00, 0x00)
+
+	// DQT
+	if 128 == len(qTables) {
+		for id := 0; id < 2; id++ {
+			dst = append(dst, 0xff, 0xdb, 0x00, 0x43, byte(id))
+			dst = append(dst, qTables[id*64:(id+1)*64]...)
+		}
+	} else {
+		dst = append(dst, 0xff, 0xdb)
+		dst = append(dst, byte((len(qTables)+2)>>8), byte(len(qTables)+2))
+		dst = append(dst, qTables...)
+	}
+
+	// SOF0 baseline, three components
+	lumaSampling := byte(0x21) // type 0: 4:2:2
+	if 1 == jpegType {
+		lumaSampling = 0x22 // type 1: 4:2:0
+	}
+	dst = append(dst, 0xff, 0xc0, 0x00, 0x11, 0x08,
+		byte(height>>8), byte(height), byte(width>>8), byte(width), 0x03,
+		0x01, lumaSampling, 0x00,
+		0x02, 0x11, 0x01,
+		0x03, 0x11, 0x01)
+
+	// DHT
+	dst = appendJpegHuffmanTable(dst, 0x00, jpegLumaDcCodelens, jpegLumaDcSymbols)
+	dst = appendJpegHuffmanTable(dst, 0x10, jpegLumaAcCodelens, jpegLumaAcSymbols)
+	dst = appendJpegHuffmanTable(dst, 0x01, jpegChromaDcCodelens, jpegChromaDcSymbols)
+	dst = appendJpegHuffmanTable(dst, 0x11, jpegChromaAcCodelens, jpegChromaAcSymbols)
+
+	// DRI
+	if 0 != restartInterval {
+		dst = append(dst, 0xff, 0xdd, 0x00, 0x04, byte(restartInterval>>8), byte(restartInterval))
+	}
+
+	// SOS
+	dst = append(dst, 0xff, 0xda, 0x00, 0x0c, 0x03,
+		0x01, 0x00,
+		0x02, 0x11,
+		0x03, 0x11,
+		0x00, 0x3f, 0x00)
+	return dst
+}
+
+func appendJpegHuffmanTable(dst []byte, tableClassID byte, codelens []byte, symbols []byte) []byte {
+	length := 2 + 1 + len(codelens) + len(symbols)
+	dst = append(dst, 0xff, 0xc4, byte(length>>8), byte(length), tableClassID)
+	dst = append(dst, codelens...)
+	return append(dst, symbols...)
+}
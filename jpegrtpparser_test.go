@@ -0,0 +1,103 @@
+package rtspclient
+
+import (
+	"bytes"
+	"encoding/binary"
+	"image"
+	"image/color"
+	"image/jpeg"
+	"testing"
+)
+
+// splitTestJpeg returns the zigzag quantization tables and the scan data of a
+// baseline JPEG written by image/jpeg.
+func splitTestJpeg(t *testing.T, src []byte) ([]byte, []byte) {
+	var qTables []byte
+	pos := 2
+	for pos+4 <= len(src) {
+		marker := src[pos+1]
+		length := int(binary.BigEndian.Uint16(src[pos+2:]))
+		segment := src[pos+4 : pos+2+length]
+		switch marker {
+		case 0xdb:
+			for len(segment) >= 65 {
+				qTables = append(qTables, segment[1:65]...)
+				segment = segment[65:]
+			}
+		case 0xda:
+			scan := src[pos+2+length:]
+			return qTables, scan[:len(scan)-2]
+		}
+		pos += 2 + length
+	}
+	t.Fatal("no scan in test image")
+	return nil, nil
+}
+
+func TestJpegRtpParser(t *testing.T) {
+	img := image.NewRGBA(image.Rect(0, 0, 64, 48))
+	for y := 0; y < 48; y++ {
+		for x := 0; x < 64; x++ {
+			img.Set(x, y, color.RGBA{uint8(x * 4), uint8(y * 5), uint8(x + y), 0xff})
+		}
+	}
+	var encoded bytes.Buffer
+	if err := jpeg.Encode(&encoded, img, &jpeg.Options{Quality: 80}); err != nil {
+		t.Fatal(err)
+	}
+	qTables, scan := splitTestJpeg(t, encoded.Bytes())
+
+	// first packet carries the tables, the rest is split at 100 bytes
+	var packets [][]byte
+	for offset := 0; offset < len(scan); offset += 100 {
+		end := offset + 100
+		if end > len(scan) {
+			end = len(scan)
+		}
+		payload := []byte{0, byte(offset >> 16), byte(offset >> 8), byte(offset), 1, 255, 64 / 8, 48 / 8}
+		if 0 == offset {
+			payload = append(payload, 0, 0, 0, byte(len(qTables)))
+			payload = append(payload, qTables...)
+		}
+		payload = append(payload, scan[offset:end]...)
+		packets = append(packets, newTestRtpPacket(end == len(scan), uint16(offset), 9000, payload))
+	}
+
+	rtpParser := newRtpParser(MediaSubsession{CodecName: "JPEG", RtpTimestampFrequency: 90000})
+	frames := parsingTestPackets(rtpParser, packets...)
+	if len(frames) != 1 {
+		t.Fatalf("got %d frames, expected 1", len(frames))
+	}
+	decoded, err := jpeg.Decode(bytes.NewReader(frames[0].Data))
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected, _ := jpeg.Decode(bytes.NewReader(encoded.Bytes()))
+	if decoded.Bounds() != expected.Bounds() {
+		t.Fatalf("bounds %v, expected %v", decoded.Bounds(), expected.Bounds())
+	}
+	for y := 0; y < 48; y++ {
+		for x := 0; x < 64; x++ {
+			if decoded.At(x, y) != expected.At(x, y) {
+				t.Fatalf("pixel %d,%d differs", x, y)
+			}
+		}
+	}
+
+	// a lost fragment drops the frame
+	frames = parsingTestPackets(rtpParser, append(packets[:1:1], packets[2:]...)...)
+	if len(frames) != 0 {
+		t.Errorf("got %d frames from an incomplete frame", len(frames))
+	}
+}
+
+func TestJpegStandardTables(t *testing.T) {
+	tables := makeJpegTables(50)
+	if tables[0] != 16 || tables[1] != 11 || tables[2] != 12 || tables[64] != 17 {
+		t.Errorf("unexpected tables for Q 50: %v", tables[:4])
+	}
+	frame := makeJpegHeaders(nil, 1, 16, 16, tables, 0)
+	if _, err := jpeg.DecodeConfig(bytes.NewReader(frame)); err != nil {
+		t.Errorf("headers not decodable: %v", err)
+	}
+}
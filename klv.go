@@ -0,0 +1,76 @@
+package rtspclient
+
+import (
+	"errors"
+)
+
+// KLVItem is one SMPTE 336M key-length-value triplet with a 16 byte
+// universal key and a BER encoded length.
+type KLVItem struct {
+	Key   [16]byte
+	Value []byte
+	Raw   []byte // the whole item, key and length included
+}
+
+var errKLVTruncated = errors.New("klv: truncated item")
+
+// ParseKLV splits a KLV unit into its items.
+func ParseKLV(src []byte) ([]KLVItem, error) {
+	var items []KLVItem
+	for 0 < len(src) {
+		if len(src) < 16 {
+			return items, errKLVTruncated
+		}
+		length, lengthSize, err := readBERLength(src[16:])
+		if nil != err {
+			return items, err
+		}
+		itemLen := 16 + lengthSize + length
+		if len(src) < itemLen {
+			return items, errKLVTruncated
+		}
+		item := KLVItem{Value: src[16+lengthSize : itemLen], Raw: src[:itemLen]}
+		copy(item.Key[:], src[:16])
+		items = append(items, item)
+		src = src[itemLen:]
+	}
+	return items, nil
+}
+
+// readBERLength reads a BER short or long form length and returns it with
+// the number of bytes it takes.
+func readBERLength(src []byte) (int, int, error) {
+	if len(src) < 1 {
+		return 0, 0, errKLVTruncated
+	}
+	if 0 == src[0]&0x80 {
+		return int(src[0]), 1, nil
+	}
+	lengthSize := int(src[0] & 0x7f)
+	if 4 < lengthSize || len(src) < 1+lengthSize {
+		return 0, 0, errors.New("klv: invalid ber length")
+	}
+	length := 0
+	for _, b := range src[1 : 1+lengthSize] {
+		length = length<<8 | int(b)
+	}
+	if length < 0 || MaxPayloadLength < length {
+		return 0, 0, errors.New("klv: invalid ber length")
+	}
+	return length, 1 + lengthSize, nil
+}
+
+// readBEROID reads a BER-OID encoded local set tag.
+func readBEROID(src []byte) (int, int, error) {
+	tag := 0
+	for index, b := range src {
+		if 4 <= index {
+			break
+		}
+		tag = tag<<7 | int(b&0x7f)
+		if 0 == b&0x80 {
+			return tag, index + 1, nil
+		}
+	}
+	return 0, 0, errors.New("klv: invalid ber-oid tag")
+}
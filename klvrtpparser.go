@@ -0,0 +1,31 @@
+package rtspclient
+
+// KlvRtpParser reassembles SMPTE 336M KLV units (smpte336m, RFC 6597). A unit
+// ends at the marker bit.
+type KlvRtpParser struct {
+	assembler frameAssembler
+}
+
+func (rtpParser *KlvRtpParser) SplitHeader(src []byte) (isCompletesFrame bool, header []byte, payload []byte) {
+	frame := rtpParser.assembler.pushMarked(src)
+	if 0 == src[1]&0x80 {
+		return false, src, src[len(src):]
+	}
+	return true, src[:RtpHeaderLen], frame
+}
+
+func (rtpParser *KlvRtpParser) ParsingRtp(header []byte, payload []byte) (naluHeaderSize int, naluSize int) {
+	return 0, len(payload)
+}
+
+// FrameInfo decodes the items of the frame. They point into its Data, which
+// the assembler reuses for the next unit once the frame is copied out.
+func (rtpParser *KlvRtpParser) FrameInfo(data *RtspData) {
+	data.KLV, _ = ParseKLV(data.Data)
+	for _, item := range data.KLV {
+		if item.IsMISB0601() {
+			data.MISB0601, _ = ParseMISB0601(item)
+			break
+		}
+	}
+}
@@ -0,0 +1,34 @@
+package rtspclient
+
+// L16RtpParser handles 16 bit linear PCM (RFC 3551). Samples arrive in
+// network byte order, the Data of its frames is always little-endian
+// whatever the byte order of the host.
+type L16RtpParser struct {
+	sampleRate int
+	channels   int
+}
+
+func newL16RtpParser(media MediaSubsession) *L16RtpParser {
+	return &L16RtpParser{
+		sampleRate: media.RtpTimestampFrequency,
+		channels:   media.channelCount(),
+	}
+}
+
+func (rtpParser *L16RtpParser) SplitHeader(src []byte) (isCompletesFrame bool, header []byte, payload []byte) {
+	return true, src[:RtpHeaderLen], src[RtpHeaderLen:]
+}
+
+func (rtpParser *L16RtpParser) ParsingRtp(header []byte, payload []byte) (naluHeaderSize int, naluSize int) {
+	return 0, len(payload)
+}
+
+// FrameInfo reports the sample timing of the frame and turns its samples
+// little-endian.
+func (rtpParser *L16RtpParser) FrameInfo(data *RtspData) {
+	for i := 0; i+1 < len(data.Data); i += 2 {
+		data.Data[i], data.Data[i+1] = data.Data[i+1], data.Data[i]
+	}
+	data.SampleRate = rtpParser.sampleRate
+	data.Samples = len(data.Data) / (2 * rtpParser.channels)
+}
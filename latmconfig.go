@@ -0,0 +1,165 @@
+package rtspclient
+
+import (
+	"encoding/hex"
+	"errors"
+)
+
+// LATMConfig is a decoded LATM StreamMuxConfig (ISO/IEC 14496-3 1.7.3).
+// Only the single program, single layer layout used over RTP is supported.
+type LATMConfig struct {
+	AudioMuxVersion           int
+	AllStreamsSameTimeFraming bool
+	NumSubFrames              int // payloads per AudioMuxElement minus one
+	FrameLengthType           int
+	OtherDataLenBits          int
+	AAC                       *AACConfig // Raw holds the byte aligned AudioSpecificConfig
+}
+
+// ParseLATMConfig decodes a StreamMuxConfig.
+func ParseLATMConfig(src []byte) (*LATMConfig, error) {
+	return readLATMConfig(newBitReader(src))
+}
+
+// ParseLATMConfigString decodes a hex StreamMuxConfig as found in the
+// "config" fmtp parameter of MP4A-LATM.
+func ParseLATMConfigString(config string) (*LATMConfig, error) {
+	src, err := hex.DecodeString(config)
+	if nil != err {
+		return nil, err
+	}
+	return ParseLATMConfig(src)
+}
+
+func readLATMValue(reader *bitReader) (int, error) {
+	bytesForValue, err := reader.readBits(2)
+	if nil != err {
+		return 0, err
+	}
+	value := 0
+	for i := 0; i <= int(bytesForValue); i++ {
+		valueTmp, err := reader.readBits(8)
+		if nil != err {
+			return 0, err
+		}
+		value = value<<8 | int(valueTmp)
+	}
+	return value, nil
+}
+
+func readLATMConfig(reader *bitReader) (*LATMConfig, error) {
+	config := &LATMConfig{}
+
+	audioMuxVersion, err := reader.readBits(1)
+	if nil != err {
+		return nil, err
+	}
+	config.AudioMuxVersion = int(audioMuxVersion)
+	if 1 == config.AudioMuxVersion {
+		audioMuxVersionA, err := reader.readBits(1)
+		if nil != err {
+			return nil, err
+		}
+		if 0 != audioMuxVersionA {
+			return nil, errors.New("latm config: audioMuxVersionA 1 is not supported")
+		}
+		// taraBufferFullness
+		if _, err := readLATMValue(reader); nil != err {
+			return nil, err
+		}
+	}
+
+	config.AllStreamsSameTimeFraming, err = reader.readFlag()
+	if nil != err {
+		return nil, err
+	}
+	numSubFrames, err := reader.readBits(6)
+	if nil != err {
+		return nil, err
+	}
+	config.NumSubFrames = int(numSubFrames)
+	numProgram, err := reader.readBits(4)
+	if nil != err {
+		return nil, err
+	}
+	numLayer, err := reader.readBits(3)
+	if nil != err {
+		return nil, err
+	}
+	if 0 != numProgram || 0 != numLayer {
+		return nil, errors.New("latm config: multiple programs or layers are not supported")
+	}
+
+	ascLen := 0
+	if 1 == config.AudioMuxVersion {
+		ascLen, err = readLATMValue(reader)
+		if nil != err {
+			return nil, err
+		}
+	}
+	ascStart := reader.offset
+	config.AAC, err = readAACConfig(reader)
+	if nil != err {
+		return nil, err
+	}
+	config.AAC.Raw = reader.copyBits(ascStart, reader.offset)
+	if 1 == config.AudioMuxVersion {
+		// fillBits
+		if err := reader.skipBits(ascLen - (reader.offset - ascStart)); nil != err {
+			return nil, err
+		}
+	}
+
+	frameLengthType, err := reader.readBits(3)
+	if nil != err {
+		return nil, err
+	}
+	config.FrameLengthType = int(frameLengthType)
+	if 0 != config.FrameLengthType {
+		return nil, errors.New("latm config: only variable frame length is supported")
+	}
+	// latmBufferFullness
+	if err := reader.skipBits(8); nil != err {
+		return nil, err
+	}
+
+	otherDataPresent, err := reader.readFlag()
+	if nil != err {
+		return nil, err
+	}
+	if otherDataPresent {
+		if 1 == config.AudioMuxVersion {
+			config.OtherDataLenBits, err = readLATMValue(reader)
+			if nil != err {
+				return nil, err
+			}
+		} else {
+			for {
+				otherDataLenEsc, err := reader.readFlag()
+				if nil != err {
+					return nil, err
+				}
+				otherDataLenTmp, err := reader.readBits(8)
+				if nil != err {
+					return nil, err
+				}
+				config.OtherDataLenBits = config.OtherDataLenBits<<8 + int(otherDataLenTmp)
+				if !otherDataLenEsc {
+					break
+				}
+			}
+		}
+	}
+
+	crcCheckPresent, err := reader.readFlag()
+	if nil != err {
+		return nil, err
+	}
+	if crcCheckPresent {
+		// crcCheckSum
+		if err := reader.skipBits(8); nil != err {
+			return nil, err
+		}
+	}
+	return config, nil
+}
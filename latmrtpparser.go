@@ -0,0 +1,139 @@
+package rtspclient
+
+import (
+	"encoding/binary"
+	"errors"
+)
+
+// LatmRtpParser depacketizes MP4A-LATM streams (RFC 3016, RFC 6416).
+// AudioMuxElements are reassembled up to the marker bit and every payload
+// inside is delivered as a raw AAC frame.
+type LatmRtpParser struct {
+	cpresent      bool
+	config        *LATMConfig
+	clockRate     int
+	frameDuration uint32 // rtp ticks per frame
+
+	muxBuf     []byte // audioMuxElements being reassembled
+	frameBuf   []byte // payloads of the last complete packet
+	frameSizes []int
+	cursor     int
+	timestamp  uint32
+}
+
+func newLatmRtpParser(media MediaSubsession) *LatmRtpParser {
+	rtpParser := &LatmRtpParser{
+		cpresent:      media.fmtpInt("cpresent", 1) == 1,
+		clockRate:     media.RtpTimestampFrequency,
+		frameDuration: 1024,
+	}
+	if config, err := ParseLATMConfigString(media.Fmtp["config"]); nil == err {
+		rtpParser.setConfig(config)
+	}
+	return rtpParser
+}
+
+// Config returns the current StreamMuxConfig, from the SDP or in band.
+// Config().AAC.Raw is the AudioSpecificConfig for muxers.
+func (rtpParser *LatmRtpParser) Config() *LATMConfig {
+	return rtpParser.config
+}
+
+func (rtpParser *LatmRtpParser) setConfig(config *LATMConfig) {
+	rtpParser.config = config
+	rtpParser.frameDuration = uint32(config.AAC.FrameLength)
+	if 0 < config.AAC.SampleRate && 0 < rtpParser.clockRate {
+		rtpParser.frameDuration = uint32(config.AAC.FrameLength * rtpParser.clockRate / config.AAC.SampleRate)
+	}
+}
+
+func (rtpParser *LatmRtpParser) SplitHeader(src []byte) (isCompletesFrame bool, header []byte, payload []byte) {
+	rtpParser.timestamp = binary.BigEndian.Uint32(src[4:8])
+	rtpParser.frameBuf = rtpParser.frameBuf[:0]
+	rtpParser.frameSizes = rtpParser.frameSizes[:0]
+	rtpParser.cursor = 0
+
+	if len(rtpParser.muxBuf)+len(src) > MaxPayloadLength {
+		rtpParser.muxBuf = rtpParser.muxBuf[:0]
+	}
+	rtpParser.muxBuf = append(rtpParser.muxBuf, src[RtpHeaderLen:]...)
+	if 0 == src[1]&0x80 {
+		// audioMuxElement continues in the next packet
+		return false, src[:RtpHeaderLen], src[len(src):]
+	}
+
+	reader := newBitReader(rtpParser.muxBuf)
+	for 8 <= reader.bitsLeft() {
+		if err := rtpParser.parsingAudioMuxElement(reader); nil != err {
+			break
+		}
+		reader.byteAlign()
+	}
+	rtpParser.muxBuf = rtpParser.muxBuf[:0]
+	return true, src[:RtpHeaderLen], rtpParser.frameBuf
+}
+
+func (rtpParser *LatmRtpParser) parsingAudioMuxElement(reader *bitReader) error {
+	if rtpParser.cpresent {
+		useSameStreamMux, err := reader.readFlag()
+		if nil != err {
+			return err
+		}
+		if !useSameStreamMux {
+			config, err := readLATMConfig(reader)
+			if nil != err {
+				return err
+			}
+			rtpParser.setConfig(config)
+		}
+	}
+	if nil == rtpParser.config {
+		return errors.New("latm: no StreamMuxConfig")
+	}
+	if !rtpParser.config.AllStreamsSameTimeFraming {
+		return errors.New("latm: allStreamsSameTimeFraming 0 is not supported")
+	}
+
+	for i := 0; i <= rtpParser.config.NumSubFrames; i++ {
+		// PayloadLengthInfo
+		frameSize := 0
+		for {
+			tmp, err := reader.readBits(8)
+			if nil != err {
+				return err
+			}
+			frameSize += int(tmp)
+			if 255 != tmp {
+				break
+			}
+		}
+		// PayloadMux
+		frame, err := reader.readBytes(frameSize)
+		if nil != err {
+			return err
+		}
+		rtpParser.frameBuf = append(rtpParser.frameBuf, frame...)
+		rtpParser.frameSizes = append(rtpParser.frameSizes, frameSize)
+	}
+	return reader.skipBits(rtpParser.config.OtherDataLenBits)
+}
+
+func (rtpParser *LatmRtpParser) ParsingRtp(header []byte, payload []byte) (naluHeaderSize int, naluSize int) {
+	if rtpParser.cursor >= len(rtpParser.frameSizes) {
+		return len(payload), len(payload)
+	}
+	naluSize = rtpParser.frameSizes[rtpParser.cursor]
+	rtpParser.cursor++
+	return 0, naluSize
+}
+
+// FrameInfo gives every frame of the packet its own timestamp.
+func (rtpParser *LatmRtpParser) FrameInfo(data *RtspData) {
+	if nil != rtpParser.config {
+		data.SampleRate, data.Samples = rtpParser.config.AAC.outputTiming()
+	}
+	if rtpParser.cursor <= 0 {
+		return
+	}
+	data.Timestamp = rtpParser.timestamp + uint32(rtpParser.cursor-1)*rtpParser.frameDuration
+}
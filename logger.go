@@ -0,0 +1,116 @@
+package rtspclient
+
+import (
+	"fmt"
+	"log"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// Logger receives the log records of the library as a message and key value
+// pairs. *slog.Logger satisfies it.
+type Logger interface {
+	Debug(msg string, args ...interface{})
+	Info(msg string, args ...interface{})
+	Warn(msg string, args ...interface{})
+	Error(msg string, args ...interface{})
+}
+
+// LogLevel is the severity of a record, with the values of slog.Level.
+type LogLevel int
+
+// Log levels.
+const (
+	LogLevelDebug LogLevel = -4
+	LogLevelInfo  LogLevel = 0
+	LogLevelWarn  LogLevel = 4
+	LogLevelError LogLevel = 8
+)
+
+func (level LogLevel) String() string {
+	switch {
+	case level < LogLevelInfo:
+		return "DEBUG"
+	case level < LogLevelWarn:
+		return "INFO"
+	case level < LogLevelError:
+		return "WARN"
+	}
+	return "ERROR"
+}
+
+type stdLogger struct {
+	output *log.Logger
+	level  LogLevel
+}
+
+// NewStdLogger creates a Logger that writes the records of level and above to
+// output, the standard logger if output is nil.
+func NewStdLogger(output *log.Logger, level LogLevel) Logger {
+	return &stdLogger{output: output, level: level}
+}
+
+func (logger *stdLogger) log(level LogLevel, msg string, args []interface{}) {
+	if level < logger.level {
+		return
+	}
+	var builder strings.Builder
+	builder.WriteString(level.String())
+	builder.WriteByte(' ')
+	builder.WriteString(msg)
+	for index := 0; index < len(args); index += 2 {
+		if index+1 == len(args) {
+			fmt.Fprintf(&builder, " !BADKEY=%v", args[index])
+			break
+		}
+		value := fmt.Sprint(args[index+1])
+		if "" == value || strings.ContainsAny(value, " \t\r\n\"=") {
+			value = strconv.Quote(value)
+		}
+		fmt.Fprintf(&builder, " %v=%s", args[index], value)
+	}
+	if nil == logger.output {
+		log.Print(builder.String())
+		return
+	}
+	logger.output.Print(builder.String())
+}
+
+func (logger *stdLogger) Debug(msg string, args ...interface{}) {
+	logger.log(LogLevelDebug, msg, args)
+}
+
+func (logger *stdLogger) Info(msg string, args ...interface{}) {
+	logger.log(LogLevelInfo, msg, args)
+}
+
+func (logger *stdLogger) Warn(msg string, args ...interface{}) {
+	logger.log(LogLevelWarn, msg, args)
+}
+
+func (logger *stdLogger) Error(msg string, args ...interface{}) {
+	logger.log(LogLevelError, msg, args)
+}
+
+type discardLogger struct{}
+
+func (discardLogger) Debug(msg string, args ...interface{}) {}
+func (discardLogger) Info(msg string, args ...interface{})  {}
+func (discardLogger) Warn(msg string, args ...interface{})  {}
+func (discardLogger) Error(msg string, args ...interface{}) {}
+
+// defaultLogger reports warnings and errors to the standard logger.
+var defaultLogger = NewStdLogger(nil, LogLevelWarn)
+
+var (
+	urlUserInfo      = regexp.MustCompile(`([A-Za-z][A-Za-z0-9+.-]*://)[^/@\s]*@`)
+	authorizationHdr = regexp.MustCompile(`(?im)^((?:proxy-)?authorization:)[^\r\n]*`)
+)
+
+// redactCredentials removes the user info of urls and the value of
+// Authorization headers from a message, for tracing.
+func redactCredentials(message string) string {
+	message = urlUserInfo.ReplaceAllString(message, "$1")
+	return authorizationHdr.ReplaceAllString(message, "$1 <redacted>")
+}
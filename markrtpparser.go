@@ -1,19 +1,87 @@
 package rtspclient
 
+// MarkerAssemblerConfig configures the generic frame assembler, which joins
+// packets up to the marker bit.
+type MarkerAssemblerConfig struct {
+	EndOnTimestampChange bool // a packet with a new RTP timestamp also ends the frame
+	MaxFrameSize         int  // larger frames are dropped, 0 for MaxPayloadLength
+}
+
+func init() {
+	RegisterMarkerCodec("BBW", MarkerAssemblerConfig{})
+}
+
+// RegisterMarkerCodec makes tracks with the given encoding name use the
+// generic frame assembler. Register codecs before Play.
+func RegisterMarkerCodec(codecName string, config MarkerAssemblerConfig) {
+	RegisterDepacketizer(codecName, func(media MediaSubsession) IDepacketizer {
+		return NewParserDepacketizer(newMarkRtpParser(config))
+	})
+}
+
+// MarkRtpParser is the generic frame assembler. A packet can complete two
+// frames when the timestamp changes on a packet with the marker bit.
 type MarkRtpParser struct {
+	config          MarkerAssemblerConfig
+	assembler       frameAssembler
+	timestamp       uint32
+	frameBuf        []byte
+	frameSizes      []int
+	frameTimestamps []uint32
+	cursor          int
+}
+
+func newMarkRtpParser(config MarkerAssemblerConfig) *MarkRtpParser {
+	rtpParser := &MarkRtpParser{config: config}
+	rtpParser.assembler.maxFrameSize = config.MaxFrameSize
+	return rtpParser
 }
 
 func (rtpParser *MarkRtpParser) SplitHeader(src []byte) (isCompletesFrame bool, header []byte, payload []byte) {
-	if len(src) < 2 {
-		return true, src[:RtpHeaderLen], src[RtpHeaderLen:]
+	rtpParser.frameBuf = rtpParser.frameBuf[:0]
+	rtpParser.frameSizes = rtpParser.frameSizes[:0]
+	rtpParser.frameTimestamps = rtpParser.frameTimestamps[:0]
+	rtpParser.cursor = 0
+
+	timestamp := uint32(src[4])<<24 | uint32(src[5])<<16 | uint32(src[6])<<8 | uint32(src[7])
+	if rtpParser.config.EndOnTimestampChange && rtpParser.assembler.midFrame && timestamp != rtpParser.timestamp {
+		rtpParser.addFrame(rtpParser.assembler.finish(), rtpParser.timestamp)
+		rtpParser.assembler.midFrame = false
 	}
+	rtpParser.timestamp = timestamp
 
-	mark := ((header[1] & 0x80) >> 7)
-	isCompletesFrame = (mark == 1)
+	frame := rtpParser.assembler.pushMarked(src)
+	if 0 != src[1]&0x80 {
+		rtpParser.addFrame(frame, timestamp)
+	}
 
-	return isCompletesFrame, src[:RtpHeaderLen], src[RtpHeaderLen:]
+	if 0 == len(rtpParser.frameSizes) {
+		return false, src, src[len(src):]
+	}
+	return true, src[:RtpHeaderLen], rtpParser.frameBuf
+}
+
+func (rtpParser *MarkRtpParser) addFrame(frame []byte, timestamp uint32) {
+	if 0 == len(frame) {
+		return
+	}
+	rtpParser.frameBuf = append(rtpParser.frameBuf, frame...)
+	rtpParser.frameSizes = append(rtpParser.frameSizes, len(frame))
+	rtpParser.frameTimestamps = append(rtpParser.frameTimestamps, timestamp)
 }
 
 func (rtpParser *MarkRtpParser) ParsingRtp(header []byte, payload []byte) (naluHeaderSize int, naluSize int) {
-	return 0, len(payload)
+	if rtpParser.cursor >= len(rtpParser.frameSizes) {
+		return 0, len(payload)
+	}
+	naluSize = rtpParser.frameSizes[rtpParser.cursor]
+	rtpParser.cursor++
+	return 0, naluSize
+}
+
+// FrameInfo sets the timestamp of a frame ended by a timestamp change.
+func (rtpParser *MarkRtpParser) FrameInfo(data *RtspData) {
+	if 0 < rtpParser.cursor && rtpParser.cursor <= len(rtpParser.frameTimestamps) {
+		data.Timestamp = rtpParser.frameTimestamps[rtpParser.cursor-1]
+	}
 }
@@ -0,0 +1,44 @@
+package rtspclient
+
+import (
+	"bytes"
+	"testing"
+)
+
+func TestMarkRtpParser(t *testing.T) {
+	rtpParser := newRtpParser(MediaSubsession{CodecName: "bbw", RtpTimestampFrequency: 90000})
+	frames := parsingTestPackets(rtpParser,
+		newTestRtpPacket(false, 1, 100, []byte{0x01, 0x02}),
+		newTestRtpPacket(true, 2, 100, []byte{0x03}))
+	if len(frames) != 1 || !bytes.Equal(frames[0].Data, []byte{0x01, 0x02, 0x03}) {
+		t.Fatalf("frame not assembled up to the marker bit: %v", frames)
+	}
+}
+
+func TestMarkRtpParserTimestampChange(t *testing.T) {
+	RegisterMarkerCodec("x-test-codec", MarkerAssemblerConfig{EndOnTimestampChange: true, MaxFrameSize: 4})
+	rtpParser := newRtpParser(MediaSubsession{CodecName: "X-TEST-CODEC", RtpTimestampFrequency: 90000})
+
+	// the second frame ends both by a timestamp change and the marker bit
+	frames := parsingTestPackets(rtpParser,
+		newTestRtpPacket(false, 1, 100, []byte{0x01, 0x02}),
+		newTestRtpPacket(false, 2, 100, []byte{0x03}),
+		newTestRtpPacket(true, 3, 200, []byte{0x04}))
+	if len(frames) != 2 {
+		t.Fatalf("got %d frames, expected 2", len(frames))
+	}
+	if frames[0].Timestamp != 100 || !bytes.Equal(frames[0].Data, []byte{0x01, 0x02, 0x03}) {
+		t.Errorf("first frame %d %x", frames[0].Timestamp, frames[0].Data)
+	}
+	if frames[1].Timestamp != 200 || !bytes.Equal(frames[1].Data, []byte{0x04}) {
+		t.Errorf("second frame %d %x", frames[1].Timestamp, frames[1].Data)
+	}
+
+	// too large frames are dropped
+	frames = parsingTestPackets(rtpParser,
+		newTestRtpPacket(false, 4, 300, []byte{0x01, 0x02, 0x03}),
+		newTestRtpPacket(true, 5, 300, []byte{0x04, 0x05}))
+	if len(frames) != 0 {
+		t.Errorf("got %d frames above the size limit", len(frames))
+	}
+}
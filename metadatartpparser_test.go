@@ -0,0 +1,176 @@
+package rtspclient
+
+import (
+	"bytes"
+	"testing"
+	"time"
+)
+
+const testOnvifMetadata = `<?xml version="1.0" encoding="UTF-8"?>
+<tt:MetadataStream xmlns:tt="http://www.onvif.org/ver10/schema" xmlns:wsnt="http://docs.oasis-open.org/wsn/b-2">
+<tt:VideoAnalytics>
+<tt:Frame UtcTime="2021-03-04T05:06:07.5Z">
+<tt:Object ObjectId="12">
+<tt:Appearance>
+<tt:Shape>
+<tt:BoundingBox left="0.1" top="0.2" right="0.3" bottom="0.4"/>
+<tt:CenterOfGravity x="0.2" y="0.3"/>
+</tt:Shape>
+<tt:Class><tt:Type Likelihood="0.9">Human</tt:Type></tt:Class>
+</tt:Appearance>
+</tt:Object>
+</tt:Frame>
+</tt:VideoAnalytics>
+<tt:Event>
+<wsnt:NotificationMessage>
+<wsnt:Topic Dialect="http://www.onvif.org/ver10/tev/topicExpression/ConcreteSet">
+tns1:RuleEngine/CellMotionDetector/Motion
+</wsnt:Topic>
+<wsnt:Message>
+<tt:Message UtcTime="2021-03-04T05:06:07" PropertyOperation="Changed">
+<tt:Source><tt:SimpleItem Name="VideoSourceConfigurationToken" Value="1"/></tt:Source>
+<tt:Data><tt:SimpleItem Name="IsMotion" Value="true"/></tt:Data>
+</tt:Message>
+</wsnt:Message>
+</wsnt:NotificationMessage>
+</tt:Event>
+</tt:MetadataStream>`
+
+func TestOnvifMetadataRtpParser(t *testing.T) {
+	rtpParser := newRtpParser(MediaSubsession{CodecName: "vnd.onvif.metadata", RtpTimestampFrequency: 90000})
+	document := []byte(testOnvifMetadata)
+	half := len(document) / 2
+	frames := parsingTestPackets(rtpParser,
+		newTestRtpPacket(false, 1, 0, document[:half]),
+		newTestRtpPacket(true, 2, 0, document[half:]))
+	if len(frames) != 1 || string(frames[0].Data) != testOnvifMetadata {
+		t.Fatalf("document not reassembled: %v", frames)
+	}
+
+	metadata := frames[0].OnvifMetadata
+	if nil == metadata || len(metadata.VideoAnalytics) != 1 || len(metadata.VideoAnalytics[0].Frames) != 1 {
+		t.Fatalf("video analytics not decoded: %+v", metadata)
+	}
+	frame := metadata.VideoAnalytics[0].Frames[0]
+	if !frame.UtcTime.Equal(time.Date(2021, 3, 4, 5, 6, 7, 500000000, time.UTC)) || len(frame.Objects) != 1 {
+		t.Fatalf("frame not decoded: %+v", frame)
+	}
+	object := frame.Objects[0]
+	if object.ObjectID != "12" || nil == object.BoundingBox || object.BoundingBox.Right != 0.3 ||
+		len(object.Classes) != 1 || object.Classes[0].Type != "Human" || object.Classes[0].Likelihood != 0.9 {
+		t.Errorf("object not decoded: %+v", object)
+	}
+
+	if len(metadata.Events) != 1 || len(metadata.Events[0].NotificationMessages) != 1 {
+		t.Fatalf("event not decoded: %+v", metadata.Events)
+	}
+	notification := metadata.Events[0].NotificationMessages[0]
+	if notification.Topic != "tns1:RuleEngine/CellMotionDetector/Motion" || notification.Message.PropertyOperation != "Changed" ||
+		notification.Message.UtcTime.IsZero() || len(notification.Message.Data) != 1 || notification.Message.Data[0].Value != "true" {
+		t.Errorf("notification not decoded: %+v", notification)
+	}
+
+	// a lost packet drops the document
+	frames = parsingTestPackets(rtpParser,
+		newTestRtpPacket(false, 3, 0, document[:half]),
+		newTestRtpPacket(true, 5, 0, document[half:]))
+	if len(frames) != 0 {
+		t.Errorf("got %d frames from an incomplete document", len(frames))
+	}
+}
+
+func TestKlvRtpParser(t *testing.T) {
+	localSet := []byte{
+		0x02, 0x08, 0x00, 0x04, 0x59, 0xf4, 0xa6, 0xaa, 0x4a, 0xa8, // precision time stamp
+		0x03, 0x04, 'M', 'I', 'S', '1', // mission id
+		0x05, 0x02, 0x71, 0xc2, // platform heading
+		0x0d, 0x04, 0x55, 0x95, 0xb6, 0x6d, // sensor latitude
+		0x0e, 0x04, 0x5b, 0x53, 0x60, 0xc4, // sensor longitude
+		0x41, 0x01, 0x0b, // version
+		0x01, 0x02, // checksum
+	}
+	unit := append(append(MISB0601Key[:], byte(len(localSet)+2)), localSet...)
+	checksum := misb0601Checksum(unit)
+	unit = append(unit, byte(checksum>>8), byte(checksum))
+
+	rtpParser := newRtpParser(MediaSubsession{CodecName: "smpte336m", RtpTimestampFrequency: 90000})
+	frames := parsingTestPackets(rtpParser,
+		newTestRtpPacket(false, 1, 9000, unit[:20]),
+		newTestRtpPacket(true, 2, 9000, unit[20:]))
+	if len(frames) != 1 || len(frames[0].KLV) != 1 || frames[0].Timestamp != 9000 {
+		t.Fatalf("klv unit not reassembled: %v", frames)
+	}
+	decoded := frames[0].MISB0601
+	if nil == decoded {
+		t.Fatal("uas datalink local set not decoded")
+	}
+	if decoded.MissionID != "MIS1" || decoded.Version != 11 ||
+		!decoded.Timestamp.Equal(time.Date(2008, 10, 24, 0, 13, 29, 913000000, time.UTC)) {
+		t.Errorf("local set not decoded: %+v", decoded)
+	}
+	if !nearlyEqual(decoded.PlatformHeading, 159.974365) || !nearlyEqual(decoded.SensorLatitude, 60.176823) ||
+		!nearlyEqual(decoded.SensorLongitude, 128.426759) {
+		t.Errorf("angles not decoded: %+v", decoded)
+	}
+
+	// corrupted value
+	unit[20] ^= 0xff
+	if _, err := ParseMISB0601(KLVItem{Key: MISB0601Key, Value: unit[17:], Raw: unit}); nil == err {
+		t.Error("checksum mismatch not detected")
+	}
+}
+
+func TestKlvFramesKeepTheirItems(t *testing.T) {
+	newUnit := func(missionID string) []byte {
+		localSet := append([]byte{0x03, byte(len(missionID))}, missionID...)
+		localSet = append(localSet, 0x01, 0x02, 0x00, 0x00) // checksum
+		unit := append(append(MISB0601Key[:], byte(len(localSet))), localSet...)
+		checksum := misb0601Checksum(unit[:len(unit)-2])
+		unit[len(unit)-2], unit[len(unit)-1] = byte(checksum>>8), byte(checksum)
+		return unit
+	}
+	rtpParser := newRtpParser(MediaSubsession{CodecName: "smpte336m", RtpTimestampFrequency: 90000})
+	first := newUnit("MIS1")
+	frames := parsingTestPackets(rtpParser, newTestRtpPacket(true, 1, 9000, first))
+	if len(frames) != 1 || len(frames[0].KLV) != 1 || nil == frames[0].MISB0601 {
+		t.Fatalf("first unit not decoded: %v", frames)
+	}
+	items, localSet := frames[0].KLV, frames[0].MISB0601
+
+	// the next unit reuses the buffer of the assembler
+	frames = parsingTestPackets(rtpParser, newTestRtpPacket(true, 2, 12000, newUnit("MIS2")))
+	if len(frames) != 1 || nil == frames[0].MISB0601 || "MIS2" != frames[0].MISB0601.MissionID {
+		t.Fatalf("second unit not decoded: %v", frames)
+	}
+	if !bytes.Equal(items[0].Raw, first) || !bytes.Equal(items[0].Value, first[17:]) {
+		t.Errorf("items of the first frame changed: %x", items[0].Raw)
+	}
+	if "MIS1" != localSet.MissionID || "MIS1" != string(localSet.Tags[3]) {
+		t.Errorf("local set of the first frame changed: %q", localSet.Tags[3])
+	}
+}
+
+func TestReadBERLength(t *testing.T) {
+	tests := []struct {
+		src    []byte
+		length int
+		size   int
+	}{
+		{[]byte{0x7f}, 127, 1},
+		{[]byte{0x81, 0x80}, 128, 2},
+		{[]byte{0x82, 0x01, 0x00}, 256, 3},
+	}
+	for _, test := range tests {
+		length, size, err := readBERLength(test.src)
+		if nil != err || length != test.length || size != test.size {
+			t.Errorf("%x: got %d, %d, %v", test.src, length, size, err)
+		}
+	}
+	if _, _, err := readBERLength([]byte{0x82, 0x01}); nil == err {
+		t.Error("truncated length accepted")
+	}
+}
+
+func nearlyEqual(a float64, b float64) bool {
+	return -0.000001 < a-b && a-b < 0.000001
+}
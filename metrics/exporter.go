@@ -0,0 +1,185 @@
+// Package metrics exports the statistics of rtsp client sessions in the
+// Prometheus text exposition format.
+package metrics
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/NodeBoy2/rtspclient"
+)
+
+// IStatsSource is a session to export, *rtspclient.RtspClientSession.
+type IStatsSource interface {
+	Stats() rtspclient.SessionStats
+}
+
+// Exporter serves the statistics of the registered sessions over HTTP.
+type Exporter struct {
+	mutex   sync.Mutex
+	sources map[IStatsSource]struct{}
+}
+
+// NewExporter creates an exporter without sessions.
+func NewExporter() *Exporter {
+	return &Exporter{sources: make(map[IStatsSource]struct{})}
+}
+
+// Register adds a session.
+func (exporter *Exporter) Register(source IStatsSource) {
+	exporter.mutex.Lock()
+	defer exporter.mutex.Unlock()
+	exporter.sources[source] = struct{}{}
+}
+
+// Unregister removes a session, e.g. after closing it.
+func (exporter *Exporter) Unregister(source IStatsSource) {
+	exporter.mutex.Lock()
+	defer exporter.mutex.Unlock()
+	delete(exporter.sources, source)
+}
+
+func (exporter *Exporter) snapshot() []rtspclient.SessionStats {
+	exporter.mutex.Lock()
+	sources := make([]IStatsSource, 0, len(exporter.sources))
+	for source := range exporter.sources {
+		sources = append(sources, source)
+	}
+	exporter.mutex.Unlock()
+
+	stats := make([]rtspclient.SessionStats, len(sources))
+	for index, source := range sources {
+		stats[index] = source.Stats()
+		stats[index].URL = StripCredentials(stats[index].URL)
+	}
+	sort.SliceStable(stats, func(i, j int) bool { return stats[i].URL < stats[j].URL })
+	return stats
+}
+
+// ServeHTTP writes the metrics of all registered sessions.
+func (exporter *Exporter) ServeHTTP(writer http.ResponseWriter, request *http.Request) {
+	writer.Header().Set("Content-Type", "text/plain; version=0.0.4; charset=utf-8")
+	exporter.WriteMetrics(writer)
+}
+
+type metric struct {
+	name   string
+	help   string
+	kind   string
+	labels string
+	value  float64
+}
+
+type trackMetric struct {
+	name  string
+	help  string
+	kind  string
+	value func(rtspclient.TrackStats) float64
+}
+
+var trackMetrics = []trackMetric{
+	{"rtsp_client_track_received_bytes_total", "Bytes of rtp received.", "counter",
+		func(track rtspclient.TrackStats) float64 { return float64(track.BytesReceived) }},
+	{"rtsp_client_track_received_packets_total", "Rtp packets received.", "counter",
+		func(track rtspclient.TrackStats) float64 { return float64(track.PacketsReceived) }},
+	{"rtsp_client_track_lost_packets", "Rtp packets expected minus received, late and duplicate packets lower it.", "gauge",
+		func(track rtspclient.TrackStats) float64 { return float64(track.PacketsLost) }},
+	{"rtsp_client_track_reordered_packets_total", "Rtp packets received out of order.", "counter",
+		func(track rtspclient.TrackStats) float64 { return float64(track.PacketsReordered) }},
+	{"rtsp_client_track_duplicated_packets_total", "Rtp packets received twice.", "counter",
+		func(track rtspclient.TrackStats) float64 { return float64(track.PacketsDuplicated) }},
+	{"rtsp_client_track_frames_total", "Frames delivered.", "counter",
+		func(track rtspclient.TrackStats) float64 { return float64(track.FramesReceived) }},
+	{"rtsp_client_track_bitrate_bits_per_second", "Bitrate over the last second.", "gauge",
+		func(track rtspclient.TrackStats) float64 { return track.Bitrate }},
+	{"rtsp_client_track_frame_rate", "Frames per second over the last second.", "gauge",
+		func(track rtspclient.TrackStats) float64 { return track.FrameRate }},
+	{"rtsp_client_track_keyframe_interval_seconds", "Time between the last two key frames.", "gauge",
+		func(track rtspclient.TrackStats) float64 { return track.KeyframeInterval.Seconds() }},
+	{"rtsp_client_track_jitter_seconds", "Interarrival jitter.", "gauge",
+		func(track rtspclient.TrackStats) float64 { return track.Jitter.Seconds() }},
+	{"rtsp_client_track_rtt_seconds", "Round trip time to the sender.", "gauge",
+		func(track rtspclient.TrackStats) float64 { return track.RTT.Seconds() }},
+	{"rtsp_client_track_frame_age_seconds", "Time since the last frame.", "gauge",
+		func(track rtspclient.TrackStats) float64 { return track.LastFrameAge.Seconds() }},
+}
+
+// WriteMetrics writes the metrics of all registered sessions in the text
+// exposition format.
+func (exporter *Exporter) WriteMetrics(writer io.Writer) error {
+	sessions := exporter.snapshot()
+	var metrics []metric
+	for _, session := range sessions {
+		labels := "url=\"" + escapeLabel(session.URL) + "\""
+		metrics = append(metrics, metric{"rtsp_client_reconnects_total", "Reconnects of the session.", "counter", labels, float64(session.Reconnects)})
+		methods := make([]string, 0, len(session.RequestLatency))
+		for method := range session.RequestLatency {
+			methods = append(methods, method)
+		}
+		sort.Strings(methods)
+		for _, method := range methods {
+			metrics = append(metrics, metric{"rtsp_client_request_latency_seconds", "Latency of the last request of a method.", "gauge",
+				labels + ",method=\"" + escapeLabel(method) + "\"", session.RequestLatency[method].Seconds()})
+		}
+	}
+	for _, trackMetric := range trackMetrics {
+		for _, session := range sessions {
+			for _, track := range session.Tracks {
+				labels := fmt.Sprintf("url=\"%s\",track=\"%d\",codec=\"%s\"", escapeLabel(session.URL), track.ChannelNum, escapeLabel(track.CodecName))
+				metrics = append(metrics, metric{trackMetric.name, trackMetric.help, trackMetric.kind, labels, trackMetric.value(track)})
+			}
+		}
+	}
+
+	// families must not be interleaved
+	sort.SliceStable(metrics, func(i, j int) bool { return metrics[i].name < metrics[j].name })
+	buffer := bufio.NewWriter(writer)
+	for index, metric := range metrics {
+		if 0 == index || metrics[index-1].name != metric.name {
+			fmt.Fprintf(buffer, "# HELP %s %s\n# TYPE %s %s\n", metric.name, metric.help, metric.name, metric.kind)
+		}
+		fmt.Fprintf(buffer, "%s{%s} %s\n", metric.name, metric.labels, strconv.FormatFloat(metric.value, 'g', -1, 64))
+	}
+	return buffer.Flush()
+}
+
+func escapeLabel(value string) string {
+	return strings.NewReplacer("\\", "\\\\", "\"", "\\\"", "\n", "\\n").Replace(value)
+}
+
+var secretQueryKeys = []string{"user", "pass", "pwd", "token", "auth", "key", "secret", "signature"}
+
+// StripCredentials removes the user info of an rtsp url and redacts query
+// parameters that look like secrets.
+func StripCredentials(rtspURL string) string {
+	urlInfo, err := url.Parse(rtspURL)
+	if nil != err {
+		if index := strings.LastIndex(rtspURL, "@"); -1 != index {
+			if scheme := strings.Index(rtspURL, "://"); -1 != scheme && scheme < index {
+				return rtspURL[:scheme+3] + rtspURL[index+1:]
+			}
+		}
+		return rtspURL
+	}
+	urlInfo.User = nil
+	if "" != urlInfo.RawQuery {
+		query := urlInfo.Query()
+		for name := range query {
+			for _, secret := range secretQueryKeys {
+				if strings.Contains(strings.ToLower(name), secret) {
+					query.Set(name, "xxxxx")
+					break
+				}
+			}
+		}
+		urlInfo.RawQuery = query.Encode()
+	}
+	return urlInfo.String()
+}
@@ -0,0 +1,195 @@
+package rtspclient
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"time"
+)
+
+// MISB0601Key is the universal key of the MISB ST 0601 UAS Datalink Local Set.
+var MISB0601Key = [16]byte{0x06, 0x0e, 0x2b, 0x34, 0x02, 0x0b, 0x01, 0x01, 0x0e, 0x01, 0x03, 0x01, 0x01, 0x00, 0x00, 0x00}
+
+// MISB ST 0601 tags decoded into MISB0601 fields.
+const (
+	MISB0601TagChecksum                = 1
+	MISB0601TagPrecisionTimeStamp      = 2
+	MISB0601TagMissionID               = 3
+	MISB0601TagPlatformHeading         = 5
+	MISB0601TagPlatformPitch           = 6
+	MISB0601TagPlatformRoll            = 7
+	MISB0601TagPlatformDesignation     = 10
+	MISB0601TagImageSourceSensor       = 11
+	MISB0601TagSensorLatitude          = 13
+	MISB0601TagSensorLongitude         = 14
+	MISB0601TagSensorAltitude          = 15
+	MISB0601TagSensorHorizontalFOV     = 16
+	MISB0601TagSensorVerticalFOV       = 17
+	MISB0601TagSensorRelativeAzimuth   = 18
+	MISB0601TagSensorRelativeElevation = 19
+	MISB0601TagSensorRelativeRoll      = 20
+	MISB0601TagSlantRange              = 21
+	MISB0601TagFrameCenterLatitude     = 23
+	MISB0601TagFrameCenterLongitude    = 24
+	MISB0601TagFrameCenterElevation    = 25
+	MISB0601TagVersion                 = 65
+)
+
+// MISB0601 is a decoded UAS Datalink Local Set. Angles are in degrees,
+// altitudes and ranges in meters. Tags holds the raw value of every tag, so
+// the presence of a field can be checked there.
+type MISB0601 struct {
+	Timestamp               time.Time
+	MissionID               string
+	PlatformDesignation     string
+	ImageSourceSensor       string
+	PlatformHeading         float64
+	PlatformPitch           float64
+	PlatformRoll            float64
+	SensorLatitude          float64
+	SensorLongitude         float64
+	SensorAltitude          float64
+	SensorHorizontalFOV     float64
+	SensorVerticalFOV       float64
+	SensorRelativeAzimuth   float64
+	SensorRelativeElevation float64
+	SensorRelativeRoll      float64
+	SlantRange              float64
+	FrameCenterLatitude     float64
+	FrameCenterLongitude    float64
+	FrameCenterElevation    float64
+	Version                 int
+	Tags                    map[int][]byte
+}
+
+// IsMISB0601 reports whether the item is a UAS Datalink Local Set.
+func (item KLVItem) IsMISB0601() bool {
+	// byte 5 is the registry version and may vary
+	return bytes.Equal(item.Key[:4], MISB0601Key[:4]) && bytes.Equal(item.Key[6:], MISB0601Key[6:])
+}
+
+// ParseMISB0601 decodes a UAS Datalink Local Set and verifies its checksum.
+func ParseMISB0601(item KLVItem) (*MISB0601, error) {
+	if !item.IsMISB0601() {
+		return nil, errors.New("misb0601: not a uas datalink local set")
+	}
+	localSet := &MISB0601{Tags: make(map[int][]byte)}
+
+	src := item.Value
+	for 0 < len(src) {
+		tag, tagSize, err := readBEROID(src)
+		if nil != err {
+			return nil, err
+		}
+		length, lengthSize, err := readBERLength(src[tagSize:])
+		if nil != err {
+			return nil, err
+		}
+		if len(src) < tagSize+lengthSize+length {
+			return nil, errKLVTruncated
+		}
+		value := src[tagSize+lengthSize : tagSize+lengthSize+length]
+		src = src[tagSize+lengthSize+length:]
+
+		if MISB0601TagChecksum == tag {
+			if 2 != len(value) || 0 != len(src) {
+				return nil, errors.New("misb0601: invalid checksum tag")
+			}
+			if misb0601Checksum(item.Raw[:len(item.Raw)-2]) != binary.BigEndian.Uint16(value) {
+				return nil, errors.New("misb0601: checksum mismatch")
+			}
+		}
+		localSet.Tags[tag] = value
+		localSet.decodeTag(tag, value)
+	}
+	return localSet, nil
+}
+
+// misb0601Checksum is the running 16 bit sum over the local set up to the
+// checksum value.
+func misb0601Checksum(src []byte) uint16 {
+	var checksum uint16
+	for index, b := range src {
+		checksum += uint16(b) << (8 * uint((index+1)%2))
+	}
+	return checksum
+}
+
+func (localSet *MISB0601) decodeTag(tag int, value []byte) {
+	switch tag {
+	case MISB0601TagPrecisionTimeStamp:
+		if 8 == len(value) {
+			microseconds := int64(binary.BigEndian.Uint64(value))
+			localSet.Timestamp = time.Unix(microseconds/1000000, microseconds%1000000*1000).UTC()
+		}
+	case MISB0601TagMissionID:
+		localSet.MissionID = string(value)
+	case MISB0601TagPlatformDesignation:
+		localSet.PlatformDesignation = string(value)
+	case MISB0601TagImageSourceSensor:
+		localSet.ImageSourceSensor = string(value)
+	case MISB0601TagPlatformHeading:
+		localSet.PlatformHeading = misbUnsigned(value, 360)
+	case MISB0601TagPlatformPitch:
+		localSet.PlatformPitch = misbSigned(value, 40)
+	case MISB0601TagPlatformRoll:
+		localSet.PlatformRoll = misbSigned(value, 100)
+	case MISB0601TagSensorLatitude:
+		localSet.SensorLatitude = misbSigned(value, 180)
+	case MISB0601TagSensorLongitude:
+		localSet.SensorLongitude = misbSigned(value, 360)
+	case MISB0601TagSensorAltitude:
+		localSet.SensorAltitude = misbUnsigned(value, 19900) - 900
+	case MISB0601TagSensorHorizontalFOV:
+		localSet.SensorHorizontalFOV = misbUnsigned(value, 180)
+	case MISB0601TagSensorVerticalFOV:
+		localSet.SensorVerticalFOV = misbUnsigned(value, 180)
+	case MISB0601TagSensorRelativeAzimuth:
+		localSet.SensorRelativeAzimuth = misbUnsigned(value, 360)
+	case MISB0601TagSensorRelativeElevation:
+		localSet.SensorRelativeElevation = misbSigned(value, 360)
+	case MISB0601TagSensorRelativeRoll:
+		localSet.SensorRelativeRoll = misbUnsigned(value, 360)
+	case MISB0601TagSlantRange:
+		localSet.SlantRange = misbUnsigned(value, 5000000)
+	case MISB0601TagFrameCenterLatitude:
+		localSet.FrameCenterLatitude = misbSigned(value, 180)
+	case MISB0601TagFrameCenterLongitude:
+		localSet.FrameCenterLongitude = misbSigned(value, 360)
+	case MISB0601TagFrameCenterElevation:
+		localSet.FrameCenterElevation = misbUnsigned(value, 19900) - 900
+	case MISB0601TagVersion:
+		if 1 == len(value) {
+			localSet.Version = int(value[0])
+		}
+	}
+}
+
+// misbUnsigned maps an unsigned integer of len(value) bytes onto 0..span.
+func misbUnsigned(value []byte, span float64) float64 {
+	if 0 == len(value) || 8 < len(value) {
+		return 0
+	}
+	var raw uint64
+	for _, b := range value {
+		raw = raw<<8 | uint64(b)
+	}
+	max := uint64(1)<<(8*uint(len(value))) - 1
+	return float64(raw) * span / float64(max)
+}
+
+// misbSigned maps a two's complement integer of len(value) bytes onto
+// -span/2..span/2.
+func misbSigned(value []byte, span float64) float64 {
+	if 0 == len(value) || 8 < len(value) {
+		return 0
+	}
+	bits := 8 * uint(len(value))
+	var raw uint64
+	for _, b := range value {
+		raw = raw<<8 | uint64(b)
+	}
+	signed := int64(raw<<(64-bits)) >> (64 - bits)
+	max := uint64(1)<<(bits-1) - 1
+	return float64(signed) * span / float64(2*max)
+}
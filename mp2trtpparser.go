@@ -0,0 +1,91 @@
+package rtspclient
+
+type mp2tFrame struct {
+	size int
+	info MP2TStreamInfo
+}
+
+// Mp2tRtpParser demuxes MPEG transport streams carried over RTP (MP2T,
+// RFC 2250). Every elementary stream of the program is delivered through the
+// data callback with RtspData.MP2T telling the streams apart; video PES come
+// as Annex B access units, AAC PES are split into single ADTS frames.
+type Mp2tRtpParser struct {
+	demuxer  *tsDemuxer
+	frameBuf []byte
+	frames   []mp2tFrame
+	cursor   int
+}
+
+func newMp2tRtpParser() *Mp2tRtpParser {
+	rtpParser := &Mp2tRtpParser{}
+	rtpParser.demuxer = newTsDemuxer(rtpParser.pushFrame)
+	return rtpParser
+}
+
+func (rtpParser *Mp2tRtpParser) SplitHeader(src []byte) (isCompletesFrame bool, header []byte, payload []byte) {
+	rtpParser.frameBuf = rtpParser.frameBuf[:0]
+	rtpParser.frames = rtpParser.frames[:0]
+	rtpParser.cursor = 0
+
+	rtpData := src[RtpHeaderLen:]
+	for len(rtpData) >= tsPacketLen {
+		if tsSyncByte != rtpData[0] {
+			// resync on the next sync byte
+			rtpData = rtpData[1:]
+			continue
+		}
+		rtpParser.demuxer.pushPacket(rtpData[:tsPacketLen])
+		rtpData = rtpData[tsPacketLen:]
+	}
+	return true, src[:RtpHeaderLen], rtpParser.frameBuf
+}
+
+func (rtpParser *Mp2tRtpParser) pushFrame(info MP2TStreamInfo, data []byte) {
+	if 0x0f != info.StreamType {
+		rtpParser.appendFrame(info, data)
+		return
+	}
+
+	// ADTS: one PES may hold several frames
+	pts := info.PTS
+	for len(data) >= 7 && 0xff == data[0] && 0xf0 == data[1]&0xf0 {
+		frameLen := int(data[3]&0x03)<<11 | int(data[4])<<3 | int(data[5]>>5)
+		if frameLen < 7 || frameLen > len(data) {
+			break
+		}
+		sampleRateIndex := int(data[2]>>2) & 0x0f
+		rtpParser.appendFrame(info, data[:frameLen])
+		data = data[frameLen:]
+
+		if -1 != pts && sampleRateIndex < len(aacSampleRates) {
+			pts += int64(1024 * 90000 / aacSampleRates[sampleRateIndex])
+			info.PTS, info.DTS = pts, pts
+		}
+	}
+}
+
+func (rtpParser *Mp2tRtpParser) appendFrame(info MP2TStreamInfo, data []byte) {
+	if 0 == len(data) {
+		return
+	}
+	rtpParser.frameBuf = append(rtpParser.frameBuf, data...)
+	rtpParser.frames = append(rtpParser.frames, mp2tFrame{size: len(data), info: info})
+}
+
+func (rtpParser *Mp2tRtpParser) ParsingRtp(header []byte, payload []byte) (naluHeaderSize int, naluSize int) {
+	if rtpParser.cursor >= len(rtpParser.frames) {
+		return len(payload), len(payload)
+	}
+	naluSize = rtpParser.frames[rtpParser.cursor].size
+	rtpParser.cursor++
+	return 0, naluSize
+}
+
+// FrameInfo tells which elementary stream the frame belongs to.
+func (rtpParser *Mp2tRtpParser) FrameInfo(data *RtspData) {
+	if rtpParser.cursor <= 0 {
+		return
+	}
+	info := rtpParser.frames[rtpParser.cursor-1].info
+	data.MP2T = &info
+}
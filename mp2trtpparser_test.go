@@ -0,0 +1,103 @@
+package rtspclient
+
+import (
+	"bytes"
+	"testing"
+)
+
+// newTestTsPackets splits a payload into TS packets, padding the last one
+// with adaptation field stuffing.
+func newTestTsPackets(pid int, continuity *int, payload []byte) []byte {
+	var packets []byte
+	for first := true; first || len(payload) > 0; first = false {
+		packet := []byte{tsSyncByte, byte(pid >> 8), byte(pid), 0x10 | byte(*continuity&0x0f)}
+		*continuity++
+		if first {
+			packet[1] |= 0x40
+		}
+		chunk := len(payload)
+		if chunk > tsPacketLen-4 {
+			chunk = tsPacketLen - 4
+		}
+		if stuffing := tsPacketLen - 4 - chunk; stuffing > 0 {
+			packet[3] |= 0x20
+			packet = append(packet, byte(stuffing-1))
+			if stuffing > 1 {
+				packet = append(packet, 0x00)
+				packet = append(packet, bytes.Repeat([]byte{0xff}, stuffing-2)...)
+			}
+		}
+		packet = append(packet, payload[:chunk]...)
+		payload = payload[chunk:]
+		packets = append(packets, packet...)
+	}
+	return packets
+}
+
+func newTestPsi(tableID byte, body []byte) []byte {
+	sectionLength := 5 + len(body) + 4
+	section := []byte{0x00, tableID, 0xb0 | byte(sectionLength>>8), byte(sectionLength), 0x00, 0x01, 0xc1, 0x00, 0x00}
+	section = append(section, body...)
+	return append(section, 0, 0, 0, 0) // CRC is not checked
+}
+
+func newTestPes(streamID byte, pts int64, dts int64, bounded bool, payload []byte) []byte {
+	pes := []byte{0x00, 0x00, 0x01, streamID, 0x00, 0x00, 0x80, 0xc0, 0x0a}
+	for i, value := range []int64{pts, dts} {
+		marker := byte(0x31 - 0x20*i)
+		pes = append(pes, marker|byte(value>>29)&0x0e, byte(value>>22), byte(value>>14)|0x01, byte(value>>7), byte(value<<1)|0x01)
+	}
+	pes = append(pes, payload...)
+	if bounded {
+		pes[4], pes[5] = byte((len(pes)-6)>>8), byte(len(pes)-6)
+	}
+	return pes
+}
+
+func TestMp2tRtpParser(t *testing.T) {
+	var patCC, pmtCC, videoCC, audioCC int
+	stream := newTestTsPackets(0, &patCC, newTestPsi(0x00, []byte{0x00, 0x01, 0xe1, 0x00}))
+	stream = append(stream, newTestTsPackets(0x100, &pmtCC, newTestPsi(0x02, []byte{
+		0xe1, 0x01, 0xf0, 0x00,
+		0x1b, 0xe1, 0x01, 0xf0, 0x00,
+		0x0f, 0xe1, 0x02, 0xf0, 0x00,
+	}))...)
+
+	accessUnit := append([]byte{0, 0, 0, 1, 0x65}, bytes.Repeat([]byte{0xaa}, 300)...)
+	stream = append(stream, newTestTsPackets(0x101, &videoCC, newTestPes(0xe0, 183600, 180000, false, accessUnit))...)
+
+	adts := []byte{0xff, 0xf1, 0x50, 0x80, 0x01, 0x5f, 0xfc, 0x11, 0x22, 0x33}
+	stream = append(stream, newTestTsPackets(0x102, &audioCC, newTestPes(0xc0, 90000, 90000, true, append(adts, adts...)))...)
+	// the next video PES flushes the first one
+	stream = append(stream, newTestTsPackets(0x101, &videoCC, newTestPes(0xe0, 187200, 183600, false, accessUnit))...)
+
+	var packets [][]byte
+	for len(stream) > 0 {
+		chunk := 7 * tsPacketLen
+		if chunk > len(stream) {
+			chunk = len(stream)
+		}
+		packets = append(packets, newTestRtpPacket(false, 1, 0, stream[:chunk]))
+		stream = stream[chunk:]
+	}
+
+	frames := parsingTestPackets(newRtpParser(MediaSubsession{CodecName: "MP2T", RtpTimestampFrequency: 90000}), packets...)
+	if len(frames) != 3 {
+		t.Fatalf("got %d frames, expected 3", len(frames))
+	}
+	for i, frame := range frames[:2] {
+		if frame.MP2T == nil || frame.MP2T.CodecName != "AAC" || !bytes.Equal(frame.Data, adts) {
+			t.Fatalf("frame %d is not an ADTS frame", i)
+		}
+	}
+	if frames[0].MP2T.PTS != 90000 || frames[1].MP2T.PTS != 90000+1024*90000/44100 {
+		t.Errorf("unexpected ADTS timestamps %d %d", frames[0].MP2T.PTS, frames[1].MP2T.PTS)
+	}
+	video := frames[2]
+	if video.MP2T.Pid != 0x101 || video.MP2T.CodecName != "H264" || !bytes.Equal(video.Data, accessUnit) {
+		t.Errorf("video access unit not reassembled")
+	}
+	if video.MP2T.PTS != 183600 || video.MP2T.DTS != 180000 {
+		t.Errorf("unexpected video timestamps %d %d", video.MP2T.PTS, video.MP2T.DTS)
+	}
+}
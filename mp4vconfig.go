@@ -0,0 +1,169 @@
+package rtspclient
+
+import (
+	"bytes"
+	"encoding/hex"
+	"errors"
+)
+
+// MP4VConfig holds the fields of an MPEG-4 Visual configuration (VOS, VO and
+// VOL headers, ISO/IEC 14496-2 6.2) as found in the MP4V-ES "config" fmtp.
+type MP4VConfig struct {
+	ProfileLevel            int // profile_and_level_indication, 0 without VOS
+	ObjectType              int // video_object_type_indication
+	Width                   int
+	Height                  int
+	TimeIncrementResolution int // vop_time_increment_resolution
+	FixedTimeIncrement      int // fixed_vop_time_increment, 0 if not fixed
+	Raw                     []byte
+}
+
+// ParseMP4VConfigString decodes the hex "config" fmtp parameter.
+func ParseMP4VConfigString(config string) (*MP4VConfig, error) {
+	src, err := hex.DecodeString(config)
+	if nil != err {
+		return nil, err
+	}
+	return ParseMP4VConfig(src)
+}
+
+// ParseMP4VConfig decodes configuration headers up to the first VOL.
+func ParseMP4VConfig(src []byte) (*MP4VConfig, error) {
+	config := &MP4VConfig{Raw: src}
+
+	if pos := bytes.Index(src, []byte{0x00, 0x00, 0x01, 0xb0}); -1 != pos && pos+4 < len(src) {
+		config.ProfileLevel = int(src[pos+4])
+	}
+
+	volPos := -1
+	for pos := 0; pos+4 <= len(src); pos++ {
+		if 0x00 == src[pos] && 0x00 == src[pos+1] && 0x01 == src[pos+2] && 0x20 == src[pos+3]&0xf0 {
+			volPos = pos
+			break
+		}
+	}
+	if -1 == volPos {
+		return nil, errors.New("mp4v config: no video object layer")
+	}
+	if err := config.parsingVol(newBitReader(src[volPos+4:])); nil != err {
+		return nil, err
+	}
+	return config, nil
+}
+
+func (config *MP4VConfig) parsingVol(reader *bitReader) error {
+	// random_accessible_vol
+	if err := reader.skipBits(1); nil != err {
+		return err
+	}
+	objectType, err := reader.readBits(8)
+	if nil != err {
+		return err
+	}
+	config.ObjectType = int(objectType)
+
+	verid := uint32(1)
+	isObjectLayerIdentifier, err := reader.readFlag()
+	if nil != err {
+		return err
+	}
+	if isObjectLayerIdentifier {
+		if verid, err = reader.readBits(4); nil != err {
+			return err
+		}
+		// video_object_layer_priority
+		if err := reader.skipBits(3); nil != err {
+			return err
+		}
+	}
+	aspectRatioInfo, err := reader.readBits(4)
+	if nil != err {
+		return err
+	}
+	if 0x0f == aspectRatioInfo {
+		// par_width, par_height
+		if err := reader.skipBits(16); nil != err {
+			return err
+		}
+	}
+	volControlParameters, err := reader.readFlag()
+	if nil != err {
+		return err
+	}
+	if volControlParameters {
+		// chroma_format, low_delay
+		if err := reader.skipBits(3); nil != err {
+			return err
+		}
+		vbvParameters, err := reader.readFlag()
+		if nil != err {
+			return err
+		}
+		if vbvParameters {
+			// bit rate, buffer size and occupancy with their markers
+			if err := reader.skipBits(79); nil != err {
+				return err
+			}
+		}
+	}
+	shape, err := reader.readBits(2)
+	if nil != err {
+		return err
+	}
+	if 3 == shape && 1 != verid {
+		// video_object_layer_shape_extension
+		if err := reader.skipBits(4); nil != err {
+			return err
+		}
+	}
+	if err := reader.skipBits(1); nil != err {
+		return err
+	}
+	resolution, err := reader.readBits(16)
+	if nil != err {
+		return err
+	}
+	config.TimeIncrementResolution = int(resolution)
+	if err := reader.skipBits(1); nil != err {
+		return err
+	}
+	fixedVopRate, err := reader.readFlag()
+	if nil != err {
+		return err
+	}
+	if fixedVopRate {
+		increment, err := reader.readBits(mp4vTimeIncrementBits(config.TimeIncrementResolution))
+		if nil != err {
+			return err
+		}
+		config.FixedTimeIncrement = int(increment)
+	}
+	if 0 == shape {
+		// rectangular: marker, width, marker, height, marker
+		if err := reader.skipBits(1); nil != err {
+			return err
+		}
+		width, err := reader.readBits(13)
+		if nil != err {
+			return err
+		}
+		if err := reader.skipBits(1); nil != err {
+			return err
+		}
+		height, err := reader.readBits(13)
+		if nil != err {
+			return err
+		}
+		config.Width, config.Height = int(width), int(height)
+	}
+	return nil
+}
+
+// mp4vTimeIncrementBits returns the size of vop_time_increment.
+func mp4vTimeIncrementBits(resolution int) int {
+	bits := 1
+	for (1 << uint(bits)) < resolution {
+		bits++
+	}
+	return bits
+}
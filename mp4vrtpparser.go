@@ -0,0 +1,80 @@
+package rtspclient
+
+// Mp4vRtpParser depacketizes MPEG-4 Visual elementary streams (MP4V-ES,
+// RFC 6416). Packets are joined up to the marker bit, and the configuration
+// from the SDP is put in front of the first I-VOP if the stream does not
+// carry it.
+type Mp4vRtpParser struct {
+	assembler  frameAssembler
+	config     *MP4VConfig
+	gotConfig  bool // configuration delivered once
+	isKeyFrame bool
+	frameBuf   []byte
+}
+
+func newMp4vRtpParser(media MediaSubsession) *Mp4vRtpParser {
+	rtpParser := &Mp4vRtpParser{}
+	rtpParser.config, _ = ParseMP4VConfigString(media.Fmtp["config"])
+	return rtpParser
+}
+
+// Config returns the configuration from the SDP, or nil if absent.
+func (rtpParser *Mp4vRtpParser) Config() *MP4VConfig {
+	return rtpParser.config
+}
+
+func (rtpParser *Mp4vRtpParser) SplitHeader(src []byte) (isCompletesFrame bool, header []byte, payload []byte) {
+	rtpData := src[RtpHeaderLen:]
+	seq := rtpSequence(src)
+	if 3 <= len(rtpData) && 0x00 == rtpData[0] && 0x00 == rtpData[1] && 0x01 == rtpData[2] {
+		// frames start with a start code
+		rtpParser.assembler.begin(seq)
+	}
+	rtpParser.assembler.push(seq, rtpData)
+
+	if 0 == src[1]&0x80 {
+		return false, src, src[len(src):]
+	}
+	frame := rtpParser.assembler.finish()
+	if nil == frame {
+		return true, src, src[len(src):]
+	}
+
+	vopType, hasVol := mp4vFrameInfo(frame)
+	rtpParser.isKeyFrame = 0 == vopType
+	if hasVol {
+		rtpParser.gotConfig = true
+	} else if rtpParser.isKeyFrame && !rtpParser.gotConfig && nil != rtpParser.config {
+		rtpParser.gotConfig = true
+		rtpParser.frameBuf = append(rtpParser.frameBuf[:0], rtpParser.config.Raw...)
+		frame = append(rtpParser.frameBuf, frame...)
+	}
+	return true, src[:RtpHeaderLen], frame
+}
+
+// mp4vFrameInfo returns the vop_coding_type of the first VOP (-1 if none)
+// and whether the frame carries a VOL header.
+func mp4vFrameInfo(frame []byte) (int, bool) {
+	hasVol := false
+	for pos := 0; pos+4 < len(frame); pos++ {
+		if 0x00 != frame[pos] || 0x00 != frame[pos+1] || 0x01 != frame[pos+2] {
+			continue
+		}
+		startCode := frame[pos+3]
+		if 0x20 == startCode&0xf0 {
+			hasVol = true
+		} else if 0xb6 == startCode {
+			return int(frame[pos+4] >> 6), hasVol
+		}
+	}
+	return -1, hasVol
+}
+
+func (rtpParser *Mp4vRtpParser) ParsingRtp(header []byte, payload []byte) (naluHeaderSize int, naluSize int) {
+	return 0, len(payload)
+}
+
+// FrameInfo marks I-VOPs.
+func (rtpParser *Mp4vRtpParser) FrameInfo(data *RtspData) {
+	data.IsKeyFrame = rtpParser.isKeyFrame
+}
@@ -0,0 +1,128 @@
+package rtspclient
+
+import (
+	"encoding/xml"
+	"strings"
+	"time"
+)
+
+// OnvifMetadataStream is a tt:MetadataStream document of an ONVIF metadata
+// track. Elements are matched by local name, whatever prefix the device uses.
+type OnvifMetadataStream struct {
+	VideoAnalytics []OnvifVideoAnalytics `xml:"VideoAnalytics"`
+	Events         []OnvifEvent          `xml:"Event"`
+	PTZ            []OnvifPTZStatus      `xml:"PTZ>PTZStatus"`
+}
+
+// OnvifVideoAnalytics groups the analytics frames of a document.
+type OnvifVideoAnalytics struct {
+	Frames []OnvifFrame `xml:"Frame"`
+}
+
+// OnvifFrame is the scene description of one video frame.
+type OnvifFrame struct {
+	UtcTime OnvifTime     `xml:"UtcTime,attr"`
+	Source  string        `xml:"Source,attr"`
+	Objects []OnvifObject `xml:"Object"`
+}
+
+// OnvifObject is an object detected in a frame.
+type OnvifObject struct {
+	ObjectID        string                `xml:"ObjectId,attr"`
+	BoundingBox     *OnvifBoundingBox     `xml:"Appearance>Shape>BoundingBox"`
+	CenterOfGravity *OnvifPoint           `xml:"Appearance>Shape>CenterOfGravity"`
+	Classes         []OnvifClassCandidate `xml:"Appearance>Class>Type"`
+}
+
+// OnvifBoundingBox is a rectangle in normalized or frame coordinates.
+type OnvifBoundingBox struct {
+	Left   float64 `xml:"left,attr"`
+	Top    float64 `xml:"top,attr"`
+	Right  float64 `xml:"right,attr"`
+	Bottom float64 `xml:"bottom,attr"`
+}
+
+// OnvifPoint is a point of a shape.
+type OnvifPoint struct {
+	X float64 `xml:"x,attr"`
+	Y float64 `xml:"y,attr"`
+}
+
+// OnvifClassCandidate is an object class with its likelihood.
+type OnvifClassCandidate struct {
+	Type       string  `xml:",chardata"`
+	Likelihood float64 `xml:"Likelihood,attr"`
+}
+
+// OnvifEvent groups the notifications of a document.
+type OnvifEvent struct {
+	NotificationMessages []OnvifNotificationMessage `xml:"NotificationMessage"`
+}
+
+// OnvifNotificationMessage is a WS-BaseNotification message, e.g. with topic
+// tns1:RuleEngine/CellMotionDetector/Motion.
+type OnvifNotificationMessage struct {
+	Topic   string       `xml:"Topic"`
+	Message OnvifMessage `xml:"Message>Message"`
+}
+
+// OnvifMessage is the tt:Message payload of a notification.
+type OnvifMessage struct {
+	UtcTime           OnvifTime         `xml:"UtcTime,attr"`
+	PropertyOperation string            `xml:"PropertyOperation,attr"`
+	Source            []OnvifSimpleItem `xml:"Source>SimpleItem"`
+	Key               []OnvifSimpleItem `xml:"Key>SimpleItem"`
+	Data              []OnvifSimpleItem `xml:"Data>SimpleItem"`
+}
+
+// OnvifSimpleItem is a name/value pair of a message.
+type OnvifSimpleItem struct {
+	Name  string `xml:"Name,attr"`
+	Value string `xml:"Value,attr"`
+}
+
+// OnvifPTZStatus is the PTZ position reported in the stream.
+type OnvifPTZStatus struct {
+	UtcTime OnvifTime   `xml:"UtcTime"`
+	PanTilt *OnvifPoint `xml:"Position>PanTilt"`
+	Zoom    *OnvifPoint `xml:"Position>Zoom"`
+}
+
+// OnvifTime is an xs:dateTime. Devices often leave out the zone, which is
+// read as UTC.
+type OnvifTime struct {
+	time.Time
+}
+
+// UnmarshalText implements encoding.TextUnmarshaler.
+func (onvifTime *OnvifTime) UnmarshalText(text []byte) error {
+	strTime := strings.TrimSpace(string(text))
+	if "" == strTime {
+		onvifTime.Time = time.Time{}
+		return nil
+	}
+	parsed, err := time.Parse(time.RFC3339Nano, strTime)
+	if nil != err {
+		parsed, err = time.Parse("2006-01-02T15:04:05.999999999", strTime)
+	}
+	if nil != err {
+		return err
+	}
+	onvifTime.Time = parsed
+	return nil
+}
+
+// ParseOnvifMetadata decodes a tt:MetadataStream document.
+func ParseOnvifMetadata(src []byte) (*OnvifMetadataStream, error) {
+	metadata := &OnvifMetadataStream{}
+	if err := xml.Unmarshal(src, metadata); nil != err {
+		return nil, err
+	}
+	for eventIndex := range metadata.Events {
+		messages := metadata.Events[eventIndex].NotificationMessages
+		for index := range messages {
+			messages[index].Topic = strings.TrimSpace(messages[index].Topic)
+		}
+	}
+	return metadata, nil
+}
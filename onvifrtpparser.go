@@ -0,0 +1,29 @@
+package rtspclient
+
+// OnvifMetadataRtpParser reassembles the XML documents of an ONVIF metadata
+// track (vnd.onvif.metadata). A document ends at the marker bit.
+type OnvifMetadataRtpParser struct {
+	assembler frameAssembler
+	metadata  *OnvifMetadataStream
+}
+
+func (rtpParser *OnvifMetadataRtpParser) SplitHeader(src []byte) (isCompletesFrame bool, header []byte, payload []byte) {
+	frame := rtpParser.assembler.pushMarked(src)
+	if 0 == src[1]&0x80 {
+		return false, src, src[len(src):]
+	}
+	rtpParser.metadata = nil
+	if nil != frame {
+		rtpParser.metadata, _ = ParseOnvifMetadata(frame)
+	}
+	return true, src[:RtpHeaderLen], frame
+}
+
+func (rtpParser *OnvifMetadataRtpParser) ParsingRtp(header []byte, payload []byte) (naluHeaderSize int, naluSize int) {
+	return 0, len(payload)
+}
+
+// FrameInfo attaches the decoded document.
+func (rtpParser *OnvifMetadataRtpParser) FrameInfo(data *RtspData) {
+	data.OnvifMetadata = rtpParser.metadata
+}
@@ -0,0 +1,50 @@
+package rtspclient
+
+// OpusRtpParser handles Opus (RFC 7587), one Opus packet per RTP packet.
+// The RTP clock is always 48 kHz whatever the coded bandwidth.
+type OpusRtpParser struct {
+	SimpleRtpParser
+}
+
+const opusSampleRate = 48000
+
+// FrameInfo reports the sample timing of the frame, read from its TOC byte
+// (RFC 6716 3.1).
+func (rtpParser *OpusRtpParser) FrameInfo(data *RtspData) {
+	data.SampleRate = opusSampleRate
+	data.Samples = OpusPacketSamples(data.Data)
+}
+
+// OpusPacketSamples returns the number of 48 kHz samples in an Opus packet,
+// or 0 if the packet is empty or malformed.
+func OpusPacketSamples(packet []byte) int {
+	if len(packet) < 1 {
+		return 0
+	}
+	toc := packet[0]
+	config := int(toc >> 3)
+
+	var frameSamples int
+	switch {
+	case config < 12: // SILK: 10, 20, 40, 60 ms
+		frameSamples = []int{480, 960, 1920, 2880}[config&0x03]
+	case config < 16: // Hybrid: 10, 20 ms
+		frameSamples = []int{480, 960}[config&0x01]
+	default: // CELT: 2.5, 5, 10, 20 ms
+		frameSamples = []int{120, 240, 480, 960}[config&0x03]
+	}
+
+	var frameCount int
+	switch toc & 0x03 {
+	case 0:
+		frameCount = 1
+	case 1, 2:
+		frameCount = 2
+	default:
+		if len(packet) < 2 {
+			return 0
+		}
+		frameCount = int(packet[1] & 0x3f)
+	}
+	return frameSamples * frameCount
+}
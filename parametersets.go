@@ -0,0 +1,281 @@
+package rtspclient
+
+import (
+	"bytes"
+	"encoding/base64"
+	"strings"
+)
+
+// H.264 and H.265 NAL unit types used here.
+const (
+	h264NalIDR = 5
+	h264NalSPS = 7
+	h264NalPPS = 8
+	hevcNalVPS = 32
+	hevcNalSPS = 33
+	hevcNalPPS = 34
+)
+
+// ParameterSets holds the parameter sets of an H.264 or H.265 track as NAL
+// units without start codes.
+type ParameterSets struct {
+	VPS [][]byte // H.265 only
+	SPS [][]byte
+	PPS [][]byte
+}
+
+// IParameterSetsInterface is implemented by the H.264 and H.265 parsers. The
+// sets start with those of the SDP and follow in-band updates.
+type IParameterSetsInterface interface {
+	ParameterSets() *ParameterSets
+}
+
+// ParseH264ParameterSets decodes the sprop-parameter-sets fmtp parameter.
+func ParseH264ParameterSets(fmtp map[string]string) *ParameterSets {
+	sets := &ParameterSets{}
+	for _, nalu := range decodeSpropNalus(fmtp["sprop-parameter-sets"]) {
+		switch nalu[0] & 0x1f {
+		case h264NalSPS:
+			sets.SPS = append(sets.SPS, nalu)
+		case h264NalPPS:
+			sets.PPS = append(sets.PPS, nalu)
+		}
+	}
+	return sets
+}
+
+// ParseH265ParameterSets decodes the sprop-vps, sprop-sps and sprop-pps fmtp
+// parameters.
+func ParseH265ParameterSets(fmtp map[string]string) *ParameterSets {
+	return &ParameterSets{
+		VPS: decodeSpropNalus(fmtp["sprop-vps"]),
+		SPS: decodeSpropNalus(fmtp["sprop-sps"]),
+		PPS: decodeSpropNalus(fmtp["sprop-pps"]),
+	}
+}
+
+// decodeSpropNalus decodes a comma separated list of base64 NAL units and
+// skips the entries that do not decode.
+func decodeSpropNalus(sprop string) [][]byte {
+	var nalus [][]byte
+	for _, strNalu := range strings.Split(sprop, ",") {
+		strNalu = strings.TrimSpace(strNalu)
+		if "" == strNalu {
+			continue
+		}
+		nalu, err := base64.StdEncoding.DecodeString(strNalu)
+		if nil != err {
+			// some cameras leave out the padding
+			nalu, err = base64.RawStdEncoding.DecodeString(strings.TrimRight(strNalu, "="))
+		}
+		if nil == err && 0 < len(nalu) {
+			nalus = append(nalus, nalu)
+		}
+	}
+	return nalus
+}
+
+// NALUs returns all parameter sets in decoding order.
+func (sets *ParameterSets) NALUs() [][]byte {
+	nalus := make([][]byte, 0, len(sets.VPS)+len(sets.SPS)+len(sets.PPS))
+	nalus = append(nalus, sets.VPS...)
+	nalus = append(nalus, sets.SPS...)
+	return append(nalus, sets.PPS...)
+}
+
+// parameterSetTracker follows the parameter sets of a track and decides
+// which access units need them in front.
+type parameterSetTracker struct {
+	sets              ParameterSets
+	hevc              bool
+	setsTimestamp     uint32 // access unit of the last in-band parameter set
+	hasSets           bool
+	injectedTimestamp uint32
+	injected          bool
+	videoInfoChanged  bool // a VPS or SPS changed since the last updateVideoInfo
+	spsInfo           *SPSInfo
+	spsInfoStale      bool
+}
+
+// update records an in-band parameter set and reports whether the NAL unit is
+// a slice of an IDR (H.264) or IRAP (H.265) picture.
+func (tracker *parameterSetTracker) update(nalu []byte, timestamp uint32) bool {
+	if 0 == len(nalu) {
+		return false
+	}
+	var nalType int
+	if tracker.hevc {
+		if len(nalu) < 2 {
+			return false
+		}
+		nalType = int(nalu[0]>>1) & 0x3f
+		switch nalType {
+		case hevcNalVPS:
+			tracker.videoInfoChanged = tracker.videoInfoChanged || !containsNalu(tracker.sets.VPS, nalu)
+			tracker.sets.VPS = replaceParameterSet(tracker.sets.VPS, nalu, hevcParameterSetID)
+		case hevcNalSPS:
+			tracker.videoInfoChanged = tracker.videoInfoChanged || !containsNalu(tracker.sets.SPS, nalu)
+			tracker.spsInfoStale = true
+			tracker.sets.SPS = replaceParameterSet(tracker.sets.SPS, nalu, hevcParameterSetID)
+		case hevcNalPPS:
+			tracker.sets.PPS = replaceParameterSet(tracker.sets.PPS, nalu, hevcParameterSetID)
+		default:
+			return 16 <= nalType && nalType <= 23
+		}
+	} else {
+		nalType = int(nalu[0] & 0x1f)
+		switch nalType {
+		case h264NalSPS:
+			tracker.videoInfoChanged = tracker.videoInfoChanged || !containsNalu(tracker.sets.SPS, nalu)
+			tracker.spsInfoStale = true
+			tracker.sets.SPS = replaceParameterSet(tracker.sets.SPS, nalu, h264ParameterSetID)
+		case h264NalPPS:
+			tracker.sets.PPS = replaceParameterSet(tracker.sets.PPS, nalu, h264ParameterSetID)
+		default:
+			return h264NalIDR == nalType
+		}
+	}
+	tracker.setsTimestamp = timestamp
+	tracker.hasSets = true
+	return false
+}
+
+// prefix returns the parameter sets to put in front of a key frame slice,
+// once per access unit that does not carry them itself.
+func (tracker *parameterSetTracker) prefix(timestamp uint32) [][]byte {
+	if (tracker.hasSets && tracker.setsTimestamp == timestamp) ||
+		(tracker.injected && tracker.injectedTimestamp == timestamp) {
+		return nil
+	}
+	tracker.injected = true
+	tracker.injectedTimestamp = timestamp
+	return tracker.sets.NALUs()
+}
+
+// updateVideoInfo decodes the first SPS (and VPS) into media if they changed.
+// It reports whether the video description of media changed.
+func (tracker *parameterSetTracker) updateVideoInfo(media *MediaSubsession) bool {
+	if !tracker.videoInfoChanged || 0 == len(tracker.sets.SPS) {
+		return false
+	}
+	tracker.videoInfoChanged = false
+
+	var info *SPSInfo
+	var err error
+	if tracker.hevc {
+		info, err = ParseH265SPS(tracker.sets.SPS[0])
+		if nil == err && 0 == info.FrameRate && 0 < len(tracker.sets.VPS) {
+			if vpsInfo, vpsErr := ParseH265VPS(tracker.sets.VPS[0]); nil == vpsErr {
+				info.FrameRate = vpsInfo.FrameRate
+			}
+		}
+	} else {
+		info, err = ParseH264SPS(tracker.sets.SPS[0])
+	}
+	if nil != err || (nil != media.VideoSPS && *media.VideoSPS == *info) {
+		return false
+	}
+
+	media.VideoSPS = info
+	media.VideoWidth = info.Width
+	media.VideoHeight = info.Height
+	if 0 < info.FrameRate {
+		media.VideoFramerate = int(info.FrameRate + 0.5)
+	}
+	return true
+}
+
+// activeSPS returns the decoded first SPS, or nil.
+func (tracker *parameterSetTracker) activeSPS() *SPSInfo {
+	if tracker.spsInfoStale {
+		tracker.spsInfoStale = false
+		tracker.spsInfo = nil
+		if 0 < len(tracker.sets.SPS) {
+			if tracker.hevc {
+				tracker.spsInfo, _ = ParseH265SPS(tracker.sets.SPS[0])
+			} else {
+				tracker.spsInfo, _ = ParseH264SPS(tracker.sets.SPS[0])
+			}
+		}
+	}
+	return tracker.spsInfo
+}
+
+func containsNalu(sets [][]byte, nalu []byte) bool {
+	for _, set := range sets {
+		if bytes.Equal(set, nalu) {
+			return true
+		}
+	}
+	return false
+}
+
+// replaceParameterSet stores nalu in place of the set with the same id, or
+// in place of all sets if the id is unknown.
+func replaceParameterSet(sets [][]byte, nalu []byte, parameterSetID func([]byte) (uint32, bool)) [][]byte {
+	id, ok := parameterSetID(nalu)
+	if !ok {
+		return [][]byte{append([]byte(nil), nalu...)}
+	}
+	for index, set := range sets {
+		if setID, setOk := parameterSetID(set); setOk && setID == id {
+			if !bytes.Equal(set, nalu) {
+				sets[index] = append([]byte(nil), nalu...)
+			}
+			return sets
+		}
+	}
+	return append(sets, append([]byte(nil), nalu...))
+}
+
+// h264ParameterSetID returns seq_parameter_set_id or pic_parameter_set_id.
+func h264ParameterSetID(nalu []byte) (uint32, bool) {
+	rbsp := unescapeRBSP(nalu[1:])
+	reader := newBitReader(rbsp)
+	if h264NalSPS == nalu[0]&0x1f {
+		// profile_idc, constraint flags, level_idc
+		if err := reader.skipBits(24); nil != err {
+			return 0, false
+		}
+	}
+	id, err := reader.readUE()
+	return id, nil == err
+}
+
+// hevcParameterSetID returns the id of a VPS or PPS. The SPS id follows the
+// profile_tier_level structure and is not looked up.
+func hevcParameterSetID(nalu []byte) (uint32, bool) {
+	if len(nalu) < 3 {
+		return 0, false
+	}
+	switch int(nalu[0]>>1) & 0x3f {
+	case hevcNalVPS:
+		return uint32(nalu[2] >> 4), true
+	case hevcNalPPS:
+		id, err := newBitReader(unescapeRBSP(nalu[2:])).readUE()
+		return id, nil == err
+	}
+	return 0, false
+}
+
+// unescapeRBSP removes the emulation prevention bytes of a NAL unit.
+func unescapeRBSP(src []byte) []byte {
+	if -1 == bytes.Index(src, []byte{0x00, 0x00, 0x03}) {
+		return src
+	}
+	dst := make([]byte, 0, len(src))
+	zeros := 0
+	for _, b := range src {
+		if 2 <= zeros && 0x03 == b {
+			zeros = 0
+			continue
+		}
+		if 0x00 == b {
+			zeros++
+		} else {
+			zeros = 0
+		}
+		dst = append(dst, b)
+	}
+	return dst
+}
@@ -0,0 +1,64 @@
+package rtspclient
+
+import (
+	"bytes"
+	"testing"
+)
+
+func TestH264ParameterSets(t *testing.T) {
+	media := MediaSubsession{
+		CodecName:             "H264",
+		RtpTimestampFrequency: 90000,
+		Fmtp:                  getFmtParame("96 packetization-mode=1; sprop-parameter-sets=Z0IAKeKQFAe2AtwEBAaQeJEV,aM48gA=="),
+	}
+	rtpParser := newRtpParser(media)
+	rtpParser.injectParameterSets = true
+	sets := rtpParser.rtpSourceHandler.(IParameterSetsInterface).ParameterSets()
+	if len(sets.SPS) != 1 || len(sets.PPS) != 1 || sets.SPS[0][0] != 0x67 || !bytes.Equal(sets.PPS[0], []byte{0x68, 0xce, 0x3c, 0x80}) {
+		t.Fatalf("sprop-parameter-sets not decoded: %x", sets.NALUs())
+	}
+
+	// STAP-A with an in-band SPS of the same id and a PPS, then the IDR slice
+	sps := []byte{0x67, 0x42, 0x00, 0x1f, 0xaa}
+	pps := []byte{0x68, 0xce, 0x3c, 0x80}
+	stap := []byte{0x18, 0x00, byte(len(sps))}
+	stap = append(stap, sps...)
+	stap = append(stap, 0x00, byte(len(pps)))
+	stap = append(stap, pps...)
+	frames := parsingTestPackets(rtpParser,
+		newTestRtpPacket(false, 1, 0, stap),
+		newTestRtpPacket(true, 2, 0, []byte{0x65, 0x88, 0x80}))
+	if len(frames) != 3 || !bytes.Equal(frames[0].Data, sps) || !bytes.Equal(frames[1].Data, pps) || !frames[2].IsKeyFrame {
+		t.Fatalf("stap-a not split: %d frames", len(frames))
+	}
+	if len(sets.SPS) != 1 || !bytes.Equal(sets.SPS[0], sps) {
+		t.Errorf("in-band sps not tracked: %x", sets.SPS)
+	}
+
+	// IDR without parameter sets gets them in front, once per access unit
+	frames = parsingTestPackets(rtpParser,
+		newTestRtpPacket(false, 3, 3000, []byte{0x65, 0x88, 0x81}),
+		newTestRtpPacket(true, 4, 3000, []byte{0x65, 0x08, 0x82}),
+		newTestRtpPacket(true, 5, 6000, []byte{0x41, 0x9a}))
+	if len(frames) != 5 {
+		t.Fatalf("got %d frames, expected 5", len(frames))
+	}
+	if !bytes.Equal(frames[0].Data, sps) || !bytes.Equal(frames[1].Data, pps) || frames[0].Timestamp != 3000 {
+		t.Errorf("parameter sets not injected: %x %x", frames[0].Data, frames[1].Data)
+	}
+	if frames[4].IsKeyFrame {
+		t.Error("non-IDR slice marked as key frame")
+	}
+}
+
+func TestH265ParameterSets(t *testing.T) {
+	fmtp := getFmtParame("96 sprop-vps=QAEMAf//AWAAAAMAkAAAAwAAAwBdlZgJ; sprop-sps=QgEBAWAAAAMAkAAAAwAAAwBdoAKAgC0WWVmkkyvAQAAAAwBAAAAHgg==; sprop-pps=RAHBcrRiQA==")
+	sets := ParseH265ParameterSets(fmtp)
+	if len(sets.VPS) != 1 || len(sets.SPS) != 1 || len(sets.PPS) != 1 {
+		t.Fatalf("sprop parameters not decoded: %x", sets.NALUs())
+	}
+	nalus := sets.NALUs()
+	if nalus[0][0]>>1 != hevcNalVPS || nalus[1][0]>>1 != hevcNalSPS || nalus[2][0]>>1 != hevcNalPPS {
+		t.Errorf("parameter sets out of order: %x", nalus)
+	}
+}
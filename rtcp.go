@@ -0,0 +1,319 @@
+package rtspclient
+
+import (
+	"encoding/binary"
+	"errors"
+	"sync/atomic"
+	"time"
+)
+
+// RTCP packet types.
+const (
+	RtcpTypeSR    = 200
+	RtcpTypeRR    = 201
+	RtcpTypeSDES  = 202
+	RtcpTypeBYE   = 203
+	RtcpTypeAPP   = 204
+	RtcpTypeRTPFB = 205
+	RtcpTypePSFB  = 206
+	RtcpTypeXR    = 207
+)
+
+// RtcpReportBlock is a reception report of an SR or RR.
+type RtcpReportBlock struct {
+	SSRC             uint32
+	FractionLost     uint8
+	CumulativeLost   int32
+	HighestSequence  uint32 // extended highest sequence number received
+	Jitter           uint32
+	LastSR           uint32 // middle 32 bits of the NTP time of the last SR
+	DelaySinceLastSR uint32 // in 1/65536 seconds
+}
+
+// RtcpSenderReport is an SR packet.
+type RtcpSenderReport struct {
+	SSRC        uint32
+	NTPTime     uint64
+	RTPTime     uint32
+	PacketCount uint32
+	OctetCount  uint32
+	Reports     []RtcpReportBlock
+	Received    time.Time // arrival time, set by the session
+}
+
+// Time returns the wall-clock time of the NTP timestamp.
+func (report *RtcpSenderReport) Time() time.Time {
+	return ntpTime(uint32(report.NTPTime>>32), uint32(report.NTPTime))
+}
+
+// RtcpReceiverReport is an RR packet.
+type RtcpReceiverReport struct {
+	SSRC    uint32
+	Reports []RtcpReportBlock
+}
+
+// RtcpSdesItem is an SDES item, e.g. type 1 (CNAME).
+type RtcpSdesItem struct {
+	Type uint8
+	Text string
+}
+
+// RtcpSdesChunk holds the SDES items of a source.
+type RtcpSdesChunk struct {
+	SSRC  uint32
+	Items []RtcpSdesItem
+}
+
+// RtcpBye is a BYE packet.
+type RtcpBye struct {
+	SSRCs  []uint32
+	Reason string
+}
+
+// RtcpApp is an APP packet.
+type RtcpApp struct {
+	Subtype uint8
+	SSRC    uint32
+	Name    string
+	Data    []byte
+}
+
+// RtcpXRBlock is a report block of an XR packet (RFC 3611).
+type RtcpXRBlock struct {
+	BlockType    uint8
+	TypeSpecific uint8
+	Data         []byte
+}
+
+// RtcpXR is an XR packet.
+type RtcpXR struct {
+	SSRC   uint32
+	Blocks []RtcpXRBlock
+}
+
+// RtcpFeedback is an RTPFB or PSFB packet (RFC 4585).
+type RtcpFeedback struct {
+	PacketType uint8
+	Format     uint8 // FMT field
+	SenderSSRC uint32
+	MediaSSRC  uint32
+	FCI        []byte
+}
+
+// RtcpCompound holds the packets of a compound RTCP packet.
+type RtcpCompound struct {
+	SenderReports   []RtcpSenderReport
+	ReceiverReports []RtcpReceiverReport
+	SourceDescs     []RtcpSdesChunk
+	Byes            []RtcpBye
+	Apps            []RtcpApp
+	XRs             []RtcpXR
+	Feedbacks       []RtcpFeedback
+}
+
+var errRtcpInvalid = errors.New("rtcp: invalid packet")
+
+// ParseRtcp decodes a compound RTCP packet. Unknown packet types are skipped.
+func ParseRtcp(src []byte) (*RtcpCompound, error) {
+	compound := &RtcpCompound{}
+	for 0 < len(src) {
+		if len(src) < 4 || 2 != src[0]>>6 {
+			return compound, errRtcpInvalid
+		}
+		packetLen := 4 * (int(binary.BigEndian.Uint16(src[2:4])) + 1)
+		if len(src) < packetLen {
+			return compound, errRtcpInvalid
+		}
+		packet := src[:packetLen]
+		src = src[packetLen:]
+		if 0 != packet[0]&0x20 {
+			// padding
+			padding := int(packet[packetLen-1])
+			if packetLen-4 < padding {
+				return compound, errRtcpInvalid
+			}
+			packet = packet[:packetLen-padding]
+		}
+		if err := compound.parsingPacket(packet); nil != err {
+			return compound, err
+		}
+	}
+	return compound, nil
+}
+
+func (compound *RtcpCompound) parsingPacket(packet []byte) error {
+	count := int(packet[0] & 0x1f)
+	body := packet[4:]
+	switch packet[1] {
+	case RtcpTypeSR:
+		if len(body) < 24 {
+			return errRtcpInvalid
+		}
+		report := RtcpSenderReport{
+			SSRC:        binary.BigEndian.Uint32(body),
+			NTPTime:     binary.BigEndian.Uint64(body[4:]),
+			RTPTime:     binary.BigEndian.Uint32(body[12:]),
+			PacketCount: binary.BigEndian.Uint32(body[16:]),
+			OctetCount:  binary.BigEndian.Uint32(body[20:]),
+		}
+		blocks, err := parsingRtcpReportBlocks(body[24:], count)
+		if nil != err {
+			return err
+		}
+		report.Reports = blocks
+		compound.SenderReports = append(compound.SenderReports, report)
+	case RtcpTypeRR:
+		if len(body) < 4 {
+			return errRtcpInvalid
+		}
+		blocks, err := parsingRtcpReportBlocks(body[4:], count)
+		if nil != err {
+			return err
+		}
+		compound.ReceiverReports = append(compound.ReceiverReports, RtcpReceiverReport{SSRC: binary.BigEndian.Uint32(body), Reports: blocks})
+	case RtcpTypeSDES:
+		for index := 0; index < count; index++ {
+			if len(body) < 4 {
+				return errRtcpInvalid
+			}
+			chunk := RtcpSdesChunk{SSRC: binary.BigEndian.Uint32(body)}
+			pos := 4
+			for pos < len(body) && 0 != body[pos] {
+				if len(body) < pos+2 || len(body) < pos+2+int(body[pos+1]) {
+					return errRtcpInvalid
+				}
+				chunk.Items = append(chunk.Items, RtcpSdesItem{Type: body[pos], Text: string(body[pos+2 : pos+2+int(body[pos+1])])})
+				pos += 2 + int(body[pos+1])
+			}
+			// the null item and the padding to the next 32 bit boundary
+			pos = (pos + 4) &^ 3
+			if len(body) < pos {
+				pos = len(body)
+			}
+			body = body[pos:]
+			compound.SourceDescs = append(compound.SourceDescs, chunk)
+		}
+	case RtcpTypeBYE:
+		if len(body) < 4*count {
+			return errRtcpInvalid
+		}
+		bye := RtcpBye{}
+		for index := 0; index < count; index++ {
+			bye.SSRCs = append(bye.SSRCs, binary.BigEndian.Uint32(body[4*index:]))
+		}
+		if reason := body[4*count:]; 0 < len(reason) && int(reason[0]) < len(reason) {
+			bye.Reason = string(reason[1 : 1+int(reason[0])])
+		}
+		compound.Byes = append(compound.Byes, bye)
+	case RtcpTypeAPP:
+		if len(body) < 8 {
+			return errRtcpInvalid
+		}
+		compound.Apps = append(compound.Apps, RtcpApp{
+			Subtype: uint8(count),
+			SSRC:    binary.BigEndian.Uint32(body),
+			Name:    string(body[4:8]),
+			Data:    body[8:],
+		})
+	case RtcpTypeRTPFB, RtcpTypePSFB:
+		if len(body) < 8 {
+			return errRtcpInvalid
+		}
+		compound.Feedbacks = append(compound.Feedbacks, RtcpFeedback{
+			PacketType: packet[1],
+			Format:     uint8(count),
+			SenderSSRC: binary.BigEndian.Uint32(body),
+			MediaSSRC:  binary.BigEndian.Uint32(body[4:]),
+			FCI:        body[8:],
+		})
+	case RtcpTypeXR:
+		if len(body) < 4 {
+			return errRtcpInvalid
+		}
+		xr := RtcpXR{SSRC: binary.BigEndian.Uint32(body)}
+		blocks := body[4:]
+		for 4 <= len(blocks) {
+			blockLen := 4 + 4*int(binary.BigEndian.Uint16(blocks[2:4]))
+			if len(blocks) < blockLen {
+				return errRtcpInvalid
+			}
+			xr.Blocks = append(xr.Blocks, RtcpXRBlock{BlockType: blocks[0], TypeSpecific: blocks[1], Data: blocks[4:blockLen]})
+			blocks = blocks[blockLen:]
+		}
+		compound.XRs = append(compound.XRs, xr)
+	}
+	return nil
+}
+
+func parsingRtcpReportBlocks(src []byte, count int) ([]RtcpReportBlock, error) {
+	if len(src) < 24*count {
+		return nil, errRtcpInvalid
+	}
+	blocks := make([]RtcpReportBlock, count)
+	for index := range blocks {
+		block := src[24*index:]
+		cumulativeLost := int32(uint32(block[5])<<16 | uint32(block[6])<<8 | uint32(block[7]))
+		if 0 != cumulativeLost&0x800000 {
+			cumulativeLost -= 0x1000000
+		}
+		blocks[index] = RtcpReportBlock{
+			SSRC:             binary.BigEndian.Uint32(block),
+			FractionLost:     block[4],
+			CumulativeLost:   cumulativeLost,
+			HighestSequence:  binary.BigEndian.Uint32(block[8:]),
+			Jitter:           binary.BigEndian.Uint32(block[12:]),
+			LastSR:           binary.BigEndian.Uint32(block[16:]),
+			DelaySinceLastSR: binary.BigEndian.Uint32(block[20:]),
+		}
+	}
+	return blocks, nil
+}
+
+func (session *RtspClientSession) parsingRtcp(channelNum int, data []byte) {
+	rtpParser, ok := session.channels()[channelNum]
+	if !ok {
+		return
+	}
+	compound, err := ParseRtcp(data)
+	if nil != err {
+		session.logger.Warn("invalid rtcp packet", "channel", channelNum, "error", err)
+	}
+	if 0 < len(compound.SenderReports) {
+		report := compound.SenderReports[len(compound.SenderReports)-1]
+		report.Received = time.Now()
+		rtpParser.senderReport.Store(&report)
+		rtpParser.stats.onSenderReport(&report, report.Received)
+		rtpParser.clock.setSenderReport(&report)
+	}
+	if roundTrip := dlrrRoundTrip(compound.XRs, session.rtcpSSRC, time.Now()); 0 < roundTrip {
+		atomic.StoreInt64(&rtpParser.counters.rtt, int64(roundTrip))
+	}
+	if 0 < len(compound.Byes) {
+		// nothing follows what is held
+		session.expireReorderBuffer(channelNum, rtpParser, time.Now(), true)
+	}
+	if nil == session.eventHandle {
+		return
+	}
+	event := newRtspEvent(RtspEventRtcp, session, data)
+	event.ChannelNum = channelNum
+	event.Rtcp = compound
+	session.eventHandle(event)
+	if 0 < len(compound.Byes) {
+		event := newRtspEvent(RtspEventBye, session, nil)
+		event.ChannelNum = channelNum
+		event.Rtcp = compound
+		session.eventHandle(event)
+	}
+}
+
+// GetSenderReport returns the last RTCP sender report of an rtp channel, nil
+// if none arrived yet. Use it to map rtp timestamps to wall-clock time.
+func (session *RtspClientSession) GetSenderReport(channelNum int) *RtcpSenderReport {
+	rtpParser, ok := session.channels()[channelNum]
+	if !ok {
+		return nil
+	}
+	report, _ := rtpParser.senderReport.Load().(*RtcpSenderReport)
+	return report
+}
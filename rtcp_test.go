@@ -0,0 +1,114 @@
+package rtspclient
+
+import (
+	"testing"
+	"time"
+)
+
+func TestParseRtcpCompound(t *testing.T) {
+	compound := []byte{
+		// SR with one report block
+		0x81, RtcpTypeSR, 0x00, 0x0c,
+		0x11, 0x22, 0x33, 0x44,
+		0xe2, 0xd2, 0x0e, 0x00, 0x80, 0x00, 0x00, 0x00,
+		0x00, 0x01, 0x00, 0x00,
+		0x00, 0x00, 0x00, 0x0a,
+		0x00, 0x00, 0x04, 0x00,
+		0x55, 0x66, 0x77, 0x88, 0x40, 0xff, 0xff, 0xfe,
+		0x00, 0x01, 0x00, 0x10, 0x00, 0x00, 0x00, 0x20,
+		0x0e, 0x00, 0x80, 0x00, 0x00, 0x00, 0x80, 0x00,
+		// SDES with a CNAME
+		0x81, RtcpTypeSDES, 0x00, 0x03,
+		0x11, 0x22, 0x33, 0x44,
+		0x01, 0x04, 'c', 'a', 'm', '1', 0x00, 0x00,
+		// BYE with a reason
+		0x81, RtcpTypeBYE, 0x00, 0x03,
+		0x11, 0x22, 0x33, 0x44,
+		0x04, 'd', 'o', 'n', 'e', 0x00, 0x00, 0x00,
+		// APP
+		0x83, RtcpTypeAPP, 0x00, 0x03,
+		0x11, 0x22, 0x33, 0x44,
+		'T', 'E', 'S', 'T',
+		0x01, 0x02, 0x03, 0x04,
+		// XR with a receiver reference time block
+		0x80, RtcpTypeXR, 0x00, 0x04,
+		0x11, 0x22, 0x33, 0x44,
+		0x04, 0x00, 0x00, 0x02,
+		0xe2, 0xd2, 0x0e, 0x00, 0x80, 0x00, 0x00, 0x00,
+	}
+	rtcp, err := ParseRtcp(compound)
+	if nil != err {
+		t.Fatal(err)
+	}
+	if 1 != len(rtcp.SenderReports) {
+		t.Fatalf("sender reports %d", len(rtcp.SenderReports))
+	}
+	report := rtcp.SenderReports[0]
+	if 0x11223344 != report.SSRC || 0x10000 != report.RTPTime || 10 != report.PacketCount || 1024 != report.OctetCount {
+		t.Errorf("sender report %+v", report)
+	}
+	if !report.Time().Equal(time.Date(2020, 8, 3, 4, 16, 0, 500000000, time.UTC)) {
+		t.Errorf("sender report time %v", report.Time())
+	}
+	if 1 != len(report.Reports) {
+		t.Fatalf("report blocks %d", len(report.Reports))
+	}
+	block := report.Reports[0]
+	if 0x55667788 != block.SSRC || 0x40 != block.FractionLost || -2 != block.CumulativeLost ||
+		0x10010 != block.HighestSequence || 0x20 != block.Jitter || 0x0e008000 != block.LastSR || 0x8000 != block.DelaySinceLastSR {
+		t.Errorf("report block %+v", block)
+	}
+	if 1 != len(rtcp.SourceDescs) || 1 != len(rtcp.SourceDescs[0].Items) || "cam1" != rtcp.SourceDescs[0].Items[0].Text {
+		t.Errorf("sdes %+v", rtcp.SourceDescs)
+	}
+	if 1 != len(rtcp.Byes) || "done" != rtcp.Byes[0].Reason || 1 != len(rtcp.Byes[0].SSRCs) {
+		t.Errorf("bye %+v", rtcp.Byes)
+	}
+	if 1 != len(rtcp.Apps) || 3 != rtcp.Apps[0].Subtype || "TEST" != rtcp.Apps[0].Name || 4 != len(rtcp.Apps[0].Data) {
+		t.Errorf("app %+v", rtcp.Apps)
+	}
+	if 1 != len(rtcp.XRs) || 1 != len(rtcp.XRs[0].Blocks) || 4 != rtcp.XRs[0].Blocks[0].BlockType || 8 != len(rtcp.XRs[0].Blocks[0].Data) {
+		t.Errorf("xr %+v", rtcp.XRs)
+	}
+}
+
+func TestParseRtcpInvalid(t *testing.T) {
+	invalid := [][]byte{
+		{0x80, RtcpTypeRR},
+		{0x40, RtcpTypeRR, 0x00, 0x01, 0x00, 0x00, 0x00, 0x00},
+		{0x80, RtcpTypeRR, 0x00, 0x02, 0x00, 0x00, 0x00, 0x00},
+		{0x81, RtcpTypeRR, 0x00, 0x01, 0x00, 0x00, 0x00, 0x00},
+	}
+	for index, packet := range invalid {
+		if _, err := ParseRtcp(packet); nil == err {
+			t.Errorf("packet %d should fail", index)
+		}
+	}
+}
+
+func TestSessionRtcpRouting(t *testing.T) {
+	var events []*RtspEvent
+	session := NewRtspClientSession(func(*RtspData) {}, func(event *RtspEvent) {
+		events = append(events, event)
+	})
+	session.addTrack(2, newRtpParser(MediaSubsession{CodecName: "PCMA"}), MediaSubsession{CodecName: "PCMA"})
+	sr := []byte{
+		0x80, RtcpTypeSR, 0x00, 0x06,
+		0x11, 0x22, 0x33, 0x44,
+		0xe2, 0xd2, 0x0e, 0x00, 0x80, 0x00, 0x00, 0x00,
+		0x00, 0x01, 0x00, 0x00,
+		0x00, 0x00, 0x00, 0x0a,
+		0x00, 0x00, 0x04, 0x00,
+	}
+	bye := []byte{0x81, RtcpTypeBYE, 0x00, 0x01, 0x11, 0x22, 0x33, 0x44}
+	session.parsingRtp(append([]byte{'$', 3, 0, byte(len(sr))}, sr...))
+	session.parsingRtp(append([]byte{'$', 3, 0, byte(len(bye))}, bye...))
+
+	report := session.GetSenderReport(2)
+	if nil == report || 0x10000 != report.RTPTime || report.Received.IsZero() {
+		t.Fatalf("sender report %+v", report)
+	}
+	if 3 != len(events) || RtspEventRtcp != events[0].EventType || RtspEventBye != events[2].EventType || 2 != events[2].ChannelNum {
+		t.Errorf("events %+v", events)
+	}
+}
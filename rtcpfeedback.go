@@ -0,0 +1,274 @@
+package rtspclient
+
+import (
+	"encoding/binary"
+	"strings"
+	"sync/atomic"
+	"time"
+)
+
+// RTCP feedback message types (RFC 4585, RFC 5104).
+const (
+	RtcpFeedbackNack = 1 // RTPFB generic NACK
+	RtcpFeedbackPli  = 1 // PSFB picture loss indication
+	RtcpFeedbackFir  = 4 // PSFB full intra request
+)
+
+// reorderMaxHold is how long packets wait in a reorder buffer for a missing
+// one in front of them.
+const reorderMaxHold = 500 * time.Millisecond
+
+// reorderMaxSize bounds the ring of a reorder buffer, half the sequence
+// number space.
+const reorderMaxSize = 1 << 15
+
+// reorderBuffer puts rtp packets back into sequence order. It holds up to
+// its size in packets, a missing packet is given up once the window is full
+// or the packets behind it waited reorderMaxHold.
+type reorderBuffer struct {
+	packets  [][]byte    // ring indexed by sequence number
+	arrivals []time.Time // arrival of the packets in the ring
+	started  bool
+	nextSeq  uint16 // next sequence number to deliver
+	highest  uint16 // highest sequence number received
+	missing  []uint16
+}
+
+// newReorderBuffer creates a buffer of at least size packets, at most
+// reorderMaxSize.
+func newReorderBuffer(size int) *reorderBuffer {
+	// a power of two, so the ring slots stay distinct across the wrap
+	ringSize := 2
+	for ringSize < size && ringSize < reorderMaxSize {
+		ringSize <<= 1
+	}
+	return &reorderBuffer{packets: make([][]byte, ringSize), arrivals: make([]time.Time, ringSize)}
+}
+
+// push queues a packet, delivers the ones now in order and returns the
+// sequence numbers this packet showed to be missing.
+func (buffer *reorderBuffer) push(packet []byte, arrival time.Time, deliver func([]byte)) []uint16 {
+	seq := rtpSequence(packet)
+	size := len(buffer.packets)
+	buffer.missing = buffer.missing[:0]
+	if !buffer.started {
+		buffer.started = true
+		buffer.nextSeq = seq
+		buffer.highest = seq - 1
+	}
+
+	offset := int16(seq - buffer.nextSeq)
+	if rtpMaxDropout <= offset || offset < -rtpMaxMisorder {
+		// the sender jumped or restarted
+		buffer.flush(deliver)
+		buffer.nextSeq = seq
+		buffer.highest = seq - 1
+	} else if 0 > offset {
+		// late or duplicate, already delivered or given up
+		return nil
+	}
+
+	if 0 < int16(seq-buffer.highest) {
+		missingSeq := buffer.highest + 1
+		if size <= int(seq-missingSeq) {
+			missingSeq = seq - uint16(size-1)
+		}
+		for ; missingSeq != seq; missingSeq++ {
+			buffer.missing = append(buffer.missing, missingSeq)
+		}
+		buffer.highest = seq
+	}
+
+	for size <= int(seq-buffer.nextSeq) {
+		buffer.skip(deliver)
+	}
+	if index := int(seq) % size; nil == buffer.packets[index] {
+		buffer.packets[index] = packet
+		buffer.arrivals[index] = arrival
+	}
+	buffer.drain(deliver)
+	buffer.expire(arrival, deliver)
+	return buffer.missing
+}
+
+// expire gives up the missing packets whose followers were held for
+// reorderMaxHold at now.
+func (buffer *reorderBuffer) expire(now time.Time, deliver func([]byte)) {
+	for 0 <= int16(buffer.highest-buffer.nextSeq) {
+		// the first held packet waited longest
+		seq := buffer.nextSeq
+		for nil == buffer.packets[int(seq)%len(buffer.packets)] && seq != buffer.highest {
+			seq++
+		}
+		if now.Sub(buffer.arrivals[int(seq)%len(buffer.arrivals)]) < reorderMaxHold {
+			return
+		}
+		buffer.skip(deliver)
+	}
+}
+
+func (buffer *reorderBuffer) drain(deliver func([]byte)) {
+	for {
+		slot := &buffer.packets[int(buffer.nextSeq)%len(buffer.packets)]
+		if nil == *slot {
+			return
+		}
+		packet := *slot
+		*slot = nil
+		buffer.nextSeq++
+		deliver(packet)
+	}
+}
+
+// skip gives up the next packet if it is missing and delivers what follows.
+func (buffer *reorderBuffer) skip(deliver func([]byte)) {
+	slot := &buffer.packets[int(buffer.nextSeq)%len(buffer.packets)]
+	packet := *slot
+	*slot = nil
+	buffer.nextSeq++
+	if nil != packet {
+		deliver(packet)
+	}
+	buffer.drain(deliver)
+}
+
+func (buffer *reorderBuffer) flush(deliver func([]byte)) {
+	for 0 <= int16(buffer.highest-buffer.nextSeq) {
+		buffer.skip(deliver)
+	}
+}
+
+// unwrapRtx restores the original packet of an RFC 4588 retransmission.
+func (rtpParser *RtpParser) unwrapRtx(src []byte) []byte {
+	src, _, err := stripRtpHeader(src)
+	if nil != err || len(src) < RtpHeaderLen+2 {
+		return nil
+	}
+	packet := make([]byte, 0, len(src)-2)
+	packet = append(packet, src[:RtpHeaderLen]...)
+	packet = append(packet, src[RtpHeaderLen+2:]...)
+	// original sequence number, payload type and ssrc
+	copy(packet[2:4], src[RtpHeaderLen:RtpHeaderLen+2])
+	packet[1] = src[1]&0x80 | byte(rtpParser.payloadFormat)
+	binary.BigEndian.PutUint32(packet[8:12], atomic.LoadUint32(&rtpParser.ssrc))
+	return packet
+}
+
+func marshalRtcpFeedback(packetType uint8, format uint8, senderSSRC uint32, mediaSSRC uint32, fci []byte) []byte {
+	packet := make([]byte, 12, 12+len(fci))
+	packet[0] = 0x80 | format
+	packet[1] = packetType
+	binary.BigEndian.PutUint16(packet[2:], uint16(2+len(fci)/4))
+	binary.BigEndian.PutUint32(packet[4:], senderSSRC)
+	binary.BigEndian.PutUint32(packet[8:], mediaSSRC)
+	return append(packet, fci...)
+}
+
+// marshalRtcpNack serializes a generic NACK of sorted sequence numbers.
+func marshalRtcpNack(senderSSRC uint32, mediaSSRC uint32, seqs []uint16) []byte {
+	var fci []byte
+	for index := 0; index < len(seqs); {
+		pid := seqs[index]
+		var blp uint16
+		for index++; index < len(seqs) && 0 < seqs[index]-pid && seqs[index]-pid <= 16; index++ {
+			blp |= 1 << (seqs[index] - pid - 1)
+		}
+		fci = append(fci, byte(pid>>8), byte(pid), byte(blp>>8), byte(blp))
+	}
+	return marshalRtcpFeedback(RtcpTypeRTPFB, RtcpFeedbackNack, senderSSRC, mediaSSRC, fci)
+}
+
+// sendRtcpFeedback sends feedback of a track behind an empty receiver report,
+// which makes the compound packet RFC 3550 requires.
+func (session *RtspClientSession) sendRtcpFeedback(channelNum int, feedback []byte) {
+	packet := append(marshalRtcpReceiverReport(session.rtcpSSRC, session.rtcpCname, nil), feedback...)
+	header := []byte{'$', byte(channelNum + 1), byte(len(packet) >> 8), byte(len(packet))}
+	if tcpConn := session.connection(); nil != tcpConn {
+		tcpConn.Send(append(header, packet...), false)
+	}
+}
+
+func (session *RtspClientSession) sendNack(channelNum int, rtpParser *RtpParser, seqs []uint16) {
+	if 0 == len(seqs) || !rtpParser.nack {
+		return
+	}
+	session.sendRtcpFeedback(channelNum, marshalRtcpNack(session.rtcpSSRC, atomic.LoadUint32(&rtpParser.ssrc), seqs))
+}
+
+// SetReorderBufferSize puts the packets of every track back into sequence
+// order, holding up to size packets for at most 500 ms. The size is rounded
+// up to a power of two, at most 32768. Tracks whose SDP offers NACK feedback
+// or RFC 4588 retransmission ask for the missing packets. 0, the default,
+// disables it. Set it before Play.
+func (session *RtspClientSession) SetReorderBufferSize(size int) {
+	session.reorderBufferSize = size
+}
+
+// RequestKeyframe asks the sender of an rtp channel for a key frame, with a
+// FIR if the SDP offers only that, else with a PLI.
+func (session *RtspClientSession) RequestKeyframe(channelNum int) error {
+	rtpParser, ok := session.channels()[channelNum]
+	if !ok {
+		return ErrNoTrack
+	}
+	mediaSSRC := atomic.LoadUint32(&rtpParser.ssrc)
+	if 0 == mediaSSRC {
+		return ErrNoRtpReceived
+	}
+	if rtpParser.keyframeFir {
+		fci := make([]byte, 8)
+		binary.BigEndian.PutUint32(fci, mediaSSRC)
+		fci[4] = byte(atomic.AddUint32(&rtpParser.firSeq, 1))
+		session.sendRtcpFeedback(channelNum, marshalRtcpFeedback(RtcpTypePSFB, RtcpFeedbackFir, session.rtcpSSRC, 0, fci))
+		return nil
+	}
+	session.sendRtcpFeedback(channelNum, marshalRtcpFeedback(RtcpTypePSFB, RtcpFeedbackPli, session.rtcpSSRC, mediaSSRC, nil))
+	return nil
+}
+
+// expireReorderBuffer delivers the packets of a track held for too long at
+// now, or all of them with flush.
+func (session *RtspClientSession) expireReorderBuffer(channelNum int, rtpParser *RtpParser, now time.Time, flush bool) {
+	if nil == rtpParser.reorder {
+		return
+	}
+	onFrame := session.frameHandler(channelNum, rtpParser, now)
+	deliver := func(packet []byte) {
+		rtpParser.parsingPacket(packet, onFrame)
+	}
+	if flush {
+		rtpParser.reorder.flush(deliver)
+	} else {
+		rtpParser.reorder.expire(now, deliver)
+	}
+}
+
+func (session *RtspClientSession) expireReorderBuffers(now time.Time, flush bool) {
+	for channelNum, rtpParser := range session.channels() {
+		session.expireReorderBuffer(channelNum, rtpParser, now, flush)
+	}
+}
+
+// setFeedback configures the feedback of a track from its SDP.
+func (rtpParser *RtpParser) setFeedback(media MediaSubsession, reorderBufferSize int) {
+	rtpParser.payloadFormat = media.PayloadFormat
+	rtpParser.rtxPayloadFormat = media.RtxPayloadFormat
+	pli, fir := false, false
+	for _, feedback := range media.RtcpFeedback {
+		switch {
+		case "nack" == feedback:
+			rtpParser.nack = true
+		case "nack pli" == feedback:
+			pli = true
+		case strings.HasPrefix(feedback, "ccm fir"):
+			fir = true
+		}
+	}
+	if 0 != media.RtxPayloadFormat {
+		rtpParser.nack = true
+	}
+	rtpParser.keyframeFir = fir && !pli
+	if 0 < reorderBufferSize {
+		rtpParser.reorder = newReorderBuffer(reorderBufferSize)
+	}
+}
@@ -0,0 +1,236 @@
+package rtspclient
+
+import (
+	"encoding/binary"
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestReorderBuffer(t *testing.T) {
+	buffer := newReorderBuffer(8)
+	var delivered []uint16
+	deliver := func(packet []byte) {
+		delivered = append(delivered, rtpSequence(packet))
+	}
+	push := func(seq uint16) []uint16 {
+		return append([]uint16(nil), buffer.push(newTestRtpPacket(false, seq, 0, nil), time.Unix(1000, 0), deliver)...)
+	}
+
+	push(65534)
+	push(65535)
+	// 0 and 1 are missing
+	if missing := push(2); 2 != len(missing) || 0 != missing[0] || 1 != missing[1] {
+		t.Errorf("missing %v", missing)
+	}
+	push(1)
+	if missing := push(3); 0 != len(missing) {
+		t.Errorf("missing %v", missing)
+	}
+	if 2 != len(delivered) {
+		t.Fatalf("delivered before the gap closed %v", delivered)
+	}
+	push(0)
+	// a duplicate and a late packet
+	push(2)
+	push(65535)
+	expected := []uint16{65534, 65535, 0, 1, 2, 3}
+	if len(expected) != len(delivered) {
+		t.Fatalf("delivered %v", delivered)
+	}
+	for index := range expected {
+		if expected[index] != delivered[index] {
+			t.Fatalf("delivered %v", delivered)
+		}
+	}
+
+	// 4 never arrives, it is given up when the window is full
+	delivered = delivered[:0]
+	for seq := uint16(5); seq < 12; seq++ {
+		push(seq)
+	}
+	if 0 != len(delivered) {
+		t.Fatalf("delivered before the window filled %v", delivered)
+	}
+	push(12)
+	if 8 != len(delivered) || 5 != delivered[0] || 12 != delivered[7] {
+		t.Errorf("delivered after giving up %v", delivered)
+	}
+
+	// a sender restart flushes the buffer
+	delivered = delivered[:0]
+	push(14)
+	push(40000)
+	if 2 != len(delivered) || 14 != delivered[0] || 40000 != delivered[1] {
+		t.Errorf("delivered after restart %v", delivered)
+	}
+}
+
+func TestReorderBufferSize(t *testing.T) {
+	buffer := newReorderBuffer(500)
+	if 512 != len(buffer.packets) {
+		t.Errorf("ring of %d packets", len(buffer.packets))
+	}
+	if ringSize := len(newReorderBuffer(1 << 20).packets); reorderMaxSize != ringSize {
+		t.Errorf("ring of %d packets above the bound", ringSize)
+	}
+
+	var delivered []uint16
+	deliver := func(packet []byte) {
+		delivered = append(delivered, rtpSequence(packet))
+	}
+	// 1 is missing while 300 packets follow
+	for seq := uint16(0); seq < 302; seq++ {
+		if 1 != seq {
+			buffer.push(newTestRtpPacket(false, seq, 0, nil), time.Unix(1000, 0), deliver)
+		}
+	}
+	if 1 != len(delivered) {
+		t.Fatalf("given up before the buffer is full, delivered %d packets", len(delivered))
+	}
+	buffer.push(newTestRtpPacket(false, 1, 0, nil), time.Unix(1000, 0), deliver)
+	if 302 != len(delivered) || 1 != delivered[1] || 301 != delivered[301] {
+		t.Errorf("delivered %d packets", len(delivered))
+	}
+}
+
+func TestReorderBufferMaxHold(t *testing.T) {
+	buffer := newReorderBuffer(64)
+	var delivered []uint16
+	deliver := func(packet []byte) {
+		delivered = append(delivered, rtpSequence(packet))
+	}
+	start := time.Unix(1000, 0)
+	// 20 ms audio packets, 1 is lost
+	buffer.push(newTestRtpPacket(false, 0, 0, nil), start, deliver)
+	for seq := uint16(2); seq < 6; seq++ {
+		buffer.push(newTestRtpPacket(false, seq, 0, nil), start.Add(time.Duration(seq)*20*time.Millisecond), deliver)
+	}
+	if 1 != len(delivered) {
+		t.Fatalf("delivered %v", delivered)
+	}
+
+	// the stream stalls, the idle check gives 1 up once 2 waited long enough
+	buffer.expire(start.Add(40*time.Millisecond+reorderMaxHold-time.Millisecond), deliver)
+	if 1 != len(delivered) {
+		t.Fatalf("given up early %v", delivered)
+	}
+	buffer.expire(start.Add(40*time.Millisecond+reorderMaxHold), deliver)
+	if 5 != len(delivered) || 2 != delivered[1] || 5 != delivered[4] {
+		t.Fatalf("delivered after the hold time %v", delivered)
+	}
+
+	// a new gap, then the end of the stream
+	buffer.push(newTestRtpPacket(false, 7, 0, nil), start.Add(time.Second), deliver)
+	buffer.flush(deliver)
+	if 6 != len(delivered) || 7 != delivered[5] {
+		t.Errorf("delivered at the end %v", delivered)
+	}
+}
+
+func TestMarshalRtcpNack(t *testing.T) {
+	packet := marshalRtcpNack(1, 2, []uint16{100, 101, 103, 116, 117, 65535, 0})
+	rtcp, err := ParseRtcp(packet)
+	if nil != err {
+		t.Fatal(err)
+	}
+	if 1 != len(rtcp.Feedbacks) {
+		t.Fatalf("feedbacks %+v", rtcp.Feedbacks)
+	}
+	feedback := rtcp.Feedbacks[0]
+	if RtcpTypeRTPFB != feedback.PacketType || RtcpFeedbackNack != feedback.Format || 1 != feedback.SenderSSRC || 2 != feedback.MediaSSRC {
+		t.Errorf("feedback %+v", feedback)
+	}
+	// 100 with 101, 103 and 116; 117; 65535 with 0
+	expected := []uint16{100, 1<<0 | 1<<2 | 1<<15, 117, 0, 65535, 1}
+	if 2*len(expected) != len(feedback.FCI) {
+		t.Fatalf("fci % x", feedback.FCI)
+	}
+	for index, value := range expected {
+		if value != binary.BigEndian.Uint16(feedback.FCI[2*index:]) {
+			t.Fatalf("fci % x", feedback.FCI)
+		}
+	}
+}
+
+func TestRtxRetransmission(t *testing.T) {
+	media := MediaSubsession{
+		CodecName:             "PCMA",
+		PayloadFormat:         96,
+		RtpTimestampFrequency: 8000,
+		RtxPayloadFormat:      97,
+	}
+	rtpParser := newRtpParser(media)
+	rtpParser.setFeedback(media, 16)
+	if !rtpParser.nack {
+		t.Errorf("rtx should enable nack")
+	}
+	var frames [][]byte
+	onFrame := func(rtspData *RtspData) {
+		frames = append(frames, append([]byte(nil), rtspData.Data...))
+	}
+
+	rtpParser.receivePacket(newTestRtpPacket(false, 10, 0, []byte{1}), time.Unix(1000, 0), onFrame)
+	missing := rtpParser.receivePacket(newTestRtpPacket(false, 12, 320, []byte{3}), time.Unix(1000, 0), onFrame)
+	if 1 != len(missing) || 11 != missing[0] {
+		t.Fatalf("missing %v", missing)
+	}
+
+	// the retransmission of 11 on its own ssrc and sequence numbers
+	rtx := newTestRtpPacket(false, 500, 160, []byte{0, 11, 2})
+	rtx[1] = 97
+	binary.BigEndian.PutUint32(rtx[8:12], 0xabcdef)
+	rtpParser.receivePacket(rtx, time.Unix(1000, 0), onFrame)
+	if 3 != len(frames) || 1 != frames[0][0] || 2 != frames[1][0] || 3 != frames[2][0] {
+		t.Errorf("frames %v", frames)
+	}
+	if 2 != rtpParser.stats.received || 0x12345678 != rtpParser.stats.ssrc {
+		t.Errorf("retransmission counted as %+v", rtpParser.stats)
+	}
+}
+
+func TestParsingSDPFeedback(t *testing.T) {
+	sdpInfo, err := parsingSDP("v=0\r\n" +
+		"o=- 0 0 IN IP4 127.0.0.1\r\n" +
+		"s=test\r\n" +
+		"t=0 0\r\n" +
+		"m=video 0 RTP/AVP 96 97\r\n" +
+		"a=rtpmap:96 H264/90000\r\n" +
+		"a=rtpmap:97 rtx/90000\r\n" +
+		"a=fmtp:97 apt=96;rtx-time=3000\r\n" +
+		"a=fmtp:96 packetization-mode=1\r\n" +
+		"a=rtcp-fb:96 nack\r\n" +
+		"a=rtcp-fb:96 nack pli\r\n" +
+		"a=rtcp-fb:* ccm fir\r\n" +
+		"a=control:trackID=0\r\n")
+	if nil != err || 1 != len(sdpInfo.Medias) {
+		t.Fatalf("sdp %+v %v", sdpInfo, err)
+	}
+	media := sdpInfo.Medias[0]
+	if 96 != media.PayloadFormat || "H264" != media.CodecName || "1" != media.Fmtp["packetization-mode"] {
+		t.Errorf("media %+v", media)
+	}
+	if 97 != media.RtxPayloadFormat || 3000 != media.RtxTime {
+		t.Errorf("rtx %d %d", media.RtxPayloadFormat, media.RtxTime)
+	}
+	if 3 != len(media.RtcpFeedback) || "nack pli" != media.RtcpFeedback[1] || "ccm fir" != media.RtcpFeedback[2] {
+		t.Errorf("rtcp feedback %q", media.RtcpFeedback)
+	}
+
+	rtpParser := newRtpParser(media)
+	rtpParser.setFeedback(media, 0)
+	if !rtpParser.nack || rtpParser.keyframeFir || nil != rtpParser.reorder {
+		t.Errorf("feedback nack %v fir %v", rtpParser.nack, rtpParser.keyframeFir)
+	}
+}
+
+func TestRequestKeyframeErrors(t *testing.T) {
+	session := NewRtspClientSession(nil, nil)
+	if err := session.RequestKeyframe(0); !errors.Is(err, ErrNoTrack) {
+		t.Errorf("no track %v", err)
+	}
+	session.addTrack(0, newRtpParser(MediaSubsession{CodecName: "H264"}), MediaSubsession{CodecName: "H264"})
+	if err := session.RequestKeyframe(0); !errors.Is(err, ErrNoRtpReceived) {
+		t.Errorf("no rtp yet %v", err)
+	}
+}
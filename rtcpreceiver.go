@@ -0,0 +1,289 @@
+package rtspclient
+
+import (
+	"encoding/binary"
+	"math/rand"
+	"os"
+	"time"
+)
+
+const (
+	rtcpMinInterval = 5 * time.Second
+	rtpSeqMod       = 1 << 16
+	rtpMaxDropout   = 3000
+	rtpMaxMisorder  = 100
+)
+
+// receptionStats keeps the reception state of a source (RFC 3550 A.1, A.3
+// and A.8) for the receiver reports.
+type receptionStats struct {
+	ssrc          uint32
+	started       bool
+	maxSeq        uint16
+	cycles        uint32
+	baseSeq       uint32
+	badSeq        uint32
+	received      uint32
+	expectedPrior uint32
+	receivedPrior uint32
+	transit       uint32
+	arrivalBase   time.Time // arrival times are measured from it, in clock units
+	jitter        float64
+	clockRate     int
+	recent        uint64 // received bits of the sequence numbers up to maxSeq
+	reordered     uint64
+	duplicated    uint64
+	lastSR        uint32 // middle 32 bits of the NTP time of the last SR
+	lastSRArrival time.Time
+}
+
+// update counts an rtp packet, header still in front.
+func (stats *receptionStats) update(packet []byte, arrival time.Time) {
+	if len(packet) < 12 {
+		return
+	}
+	seq := binary.BigEndian.Uint16(packet[2:4])
+	timestamp := binary.BigEndian.Uint32(packet[4:8])
+	ssrc := binary.BigEndian.Uint32(packet[8:12])
+	if !stats.started || ssrc != stats.ssrc {
+		stats.initSequence(seq)
+		stats.ssrc = ssrc
+		stats.started = true
+		stats.arrivalBase = arrival
+	} else {
+		delta := seq - stats.maxSeq
+		if 0 == delta {
+			stats.duplicated++
+			return
+		} else if delta < rtpMaxDropout {
+			if seq < stats.maxSeq {
+				// sequence number wrapped
+				stats.cycles += rtpSeqMod
+			}
+			stats.maxSeq = seq
+			stats.recent = stats.recent<<delta | 1
+		} else if delta <= rtpSeqMod-rtpMaxMisorder {
+			if uint32(seq) != stats.badSeq {
+				// large jump, wait for the next packet to confirm it
+				stats.badSeq = (uint32(seq) + 1) & (rtpSeqMod - 1)
+				return
+			}
+			// the sender restarted
+			stats.initSequence(seq)
+		} else if behind := stats.maxSeq - seq; 64 > behind && 0 != stats.recent&(1<<behind) {
+			stats.duplicated++
+			return
+		} else {
+			stats.reordered++
+			if 64 > behind {
+				stats.recent |= 1 << behind
+			}
+		}
+	}
+	stats.received++
+
+	if 0 < stats.clockRate {
+		// interarrival jitter in timestamp units, modulo 2^32 as the timestamps
+		elapsed := arrival.Sub(stats.arrivalBase)
+		arrivalUnits := int64(elapsed/time.Second)*int64(stats.clockRate) + int64(elapsed%time.Second)*int64(stats.clockRate)/int64(time.Second)
+		transit := uint32(arrivalUnits) - timestamp
+		if 1 < stats.received {
+			diff := int64(int32(transit - stats.transit))
+			if diff < 0 {
+				diff = -diff
+			}
+			stats.jitter += (float64(diff) - stats.jitter) / 16
+		}
+		stats.transit = transit
+	}
+}
+
+func (stats *receptionStats) initSequence(seq uint16) {
+	stats.baseSeq = uint32(seq)
+	stats.maxSeq = seq
+	stats.recent = 1
+	stats.badSeq = rtpSeqMod + 1
+	stats.cycles = 0
+	stats.received = 0
+	stats.receivedPrior = 0
+	stats.expectedPrior = 0
+}
+
+func (stats *receptionStats) onSenderReport(report *RtcpSenderReport, arrival time.Time) {
+	stats.lastSR = uint32(report.NTPTime >> 16)
+	stats.lastSRArrival = arrival
+}
+
+func (stats *receptionStats) expected() uint32 {
+	return stats.cycles + uint32(stats.maxSeq) - stats.baseSeq + 1
+}
+
+func (stats *receptionStats) cumulativeLost() int64 {
+	if !stats.started {
+		return 0
+	}
+	return int64(stats.expected()) - int64(stats.received)
+}
+
+// reportBlock builds the reception report and starts a new report interval.
+func (stats *receptionStats) reportBlock(now time.Time) RtcpReportBlock {
+	extendedMax := stats.cycles + uint32(stats.maxSeq)
+	expected := stats.expected()
+	lost := stats.cumulativeLost()
+	if lost > 0x7fffff {
+		lost = 0x7fffff
+	} else if lost < -0x800000 {
+		lost = -0x800000
+	}
+
+	expectedInterval := expected - stats.expectedPrior
+	receivedInterval := stats.received - stats.receivedPrior
+	stats.expectedPrior = expected
+	stats.receivedPrior = stats.received
+	var fraction uint8
+	if lostInterval := int64(expectedInterval) - int64(receivedInterval); 0 < expectedInterval && 0 < lostInterval {
+		fraction = uint8((lostInterval << 8) / int64(expectedInterval))
+	}
+
+	block := RtcpReportBlock{
+		SSRC:            stats.ssrc,
+		FractionLost:    fraction,
+		CumulativeLost:  int32(lost),
+		HighestSequence: extendedMax,
+		Jitter:          uint32(stats.jitter),
+		LastSR:          stats.lastSR,
+	}
+	if 0 != stats.lastSR {
+		block.DelaySinceLastSR = uint32(now.Sub(stats.lastSRArrival) * 65536 / time.Second)
+	}
+	return block
+}
+
+// rtcpInterval returns the randomized report interval of a receiver, half
+// the minimum before the first report (RFC 3550 6.3.1).
+func rtcpInterval(initial bool) time.Duration {
+	interval := rtcpMinInterval
+	if initial {
+		interval /= 2
+	}
+	// randomize in [0.5, 1.5] and compensate for the timer reconsideration
+	interval = time.Duration(float64(interval) * (rand.Float64() + 0.5) / 1.21828)
+	return interval
+}
+
+// marshalRtcpReceiverReport serializes an RR followed by the SDES CNAME, the
+// smallest compound packet a receiver may send.
+func marshalRtcpReceiverReport(ssrc uint32, cname string, blocks []RtcpReportBlock) []byte {
+	if 31 < len(blocks) {
+		blocks = blocks[:31]
+	}
+	packet := make([]byte, 8, 8+24*len(blocks)+12+len(cname))
+	packet[0] = 0x80 | byte(len(blocks))
+	packet[1] = RtcpTypeRR
+	binary.BigEndian.PutUint16(packet[2:], uint16(1+6*len(blocks)))
+	binary.BigEndian.PutUint32(packet[4:], ssrc)
+	for _, block := range blocks {
+		var buf [24]byte
+		binary.BigEndian.PutUint32(buf[0:], block.SSRC)
+		binary.BigEndian.PutUint32(buf[4:], uint32(block.CumulativeLost)&0xffffff)
+		buf[4] = block.FractionLost
+		binary.BigEndian.PutUint32(buf[8:], block.HighestSequence)
+		binary.BigEndian.PutUint32(buf[12:], block.Jitter)
+		binary.BigEndian.PutUint32(buf[16:], block.LastSR)
+		binary.BigEndian.PutUint32(buf[20:], block.DelaySinceLastSR)
+		packet = append(packet, buf[:]...)
+	}
+
+	if 255 < len(cname) {
+		cname = cname[:255]
+	}
+	sdesStart := len(packet)
+	packet = append(packet, 0x81, RtcpTypeSDES, 0, 0)
+	packet = append(packet, byte(ssrc>>24), byte(ssrc>>16), byte(ssrc>>8), byte(ssrc))
+	packet = append(packet, 1, byte(len(cname)))
+	packet = append(packet, cname...)
+	// null item, padded to 32 bits
+	packet = append(packet, 0)
+	for 0 != (len(packet)-sdesStart)%4 {
+		packet = append(packet, 0)
+	}
+	binary.BigEndian.PutUint16(packet[sdesStart+2:], uint16((len(packet)-sdesStart)/4-1))
+	return packet
+}
+
+// RTCP XR block types (RFC 3611).
+const (
+	rtcpXRReceiverReferenceTime = 4
+	rtcpXRDLRR                  = 5
+)
+
+// ntpTimestamp returns the 64 bit NTP timestamp of a time.
+func ntpTimestamp(t time.Time) uint64 {
+	seconds := uint64(t.Unix() + ntpUnixEpochOffset)
+	fraction := (uint64(t.Nanosecond()) << 32) / uint64(time.Second)
+	return seconds<<32 | fraction
+}
+
+// marshalRtcpReceiverReferenceTime serializes an XR with the receiver
+// reference time, the sender answers with a DLRR block to measure the RTT.
+func marshalRtcpReceiverReferenceTime(ssrc uint32, now time.Time) []byte {
+	packet := make([]byte, 20)
+	packet[0] = 0x80
+	packet[1] = RtcpTypeXR
+	binary.BigEndian.PutUint16(packet[2:], 4)
+	binary.BigEndian.PutUint32(packet[4:], ssrc)
+	packet[8] = rtcpXRReceiverReferenceTime
+	binary.BigEndian.PutUint16(packet[10:], 2)
+	binary.BigEndian.PutUint64(packet[12:], ntpTimestamp(now))
+	return packet
+}
+
+// dlrrRoundTrip returns the round trip time of the DLRR sub-block of ssrc in
+// the XRs, 0 if there is none.
+func dlrrRoundTrip(xrs []RtcpXR, ssrc uint32, now time.Time) time.Duration {
+	for _, xr := range xrs {
+		for _, block := range xr.Blocks {
+			if rtcpXRDLRR != block.BlockType {
+				continue
+			}
+			for subBlock := block.Data; 12 <= len(subBlock); subBlock = subBlock[12:] {
+				lastRR := binary.BigEndian.Uint32(subBlock[4:])
+				if ssrc != binary.BigEndian.Uint32(subBlock) || 0 == lastRR {
+					continue
+				}
+				delay := binary.BigEndian.Uint32(subBlock[8:])
+				roundTrip := int32(uint32(ntpTimestamp(now)>>16) - lastRR - delay)
+				if 0 > roundTrip {
+					return 0
+				}
+				return time.Duration(int64(roundTrip) * int64(time.Second) / 65536)
+			}
+		}
+	}
+	return 0
+}
+
+func rtcpCname() string {
+	hostname, err := os.Hostname()
+	if nil != err || "" == hostname {
+		return "rtspclient"
+	}
+	return "rtspclient@" + hostname
+}
+
+// sendReceiverReports sends a receiver report for every track that received
+// rtp on its interleaved rtcp channel.
+func (session *RtspClientSession) sendReceiverReports() {
+	now := time.Now()
+	for channelNum, rtpParser := range session.channels() {
+		if !rtpParser.stats.started {
+			continue
+		}
+		packet := marshalRtcpReceiverReport(session.rtcpSSRC, session.rtcpCname, []RtcpReportBlock{rtpParser.stats.reportBlock(now)})
+		packet = append(packet, marshalRtcpReceiverReferenceTime(session.rtcpSSRC, now)...)
+		header := []byte{'$', byte(channelNum + 1), byte(len(packet) >> 8), byte(len(packet))}
+		if tcpConn := session.connection(); nil != tcpConn {
+			tcpConn.Send(append(header, packet...), false)
+		}
+	}
+}
@@ -0,0 +1,119 @@
+package rtspclient
+
+import (
+	"math"
+	"testing"
+	"time"
+)
+
+func TestReceptionStatsLoss(t *testing.T) {
+	stats := receptionStats{clockRate: 8000}
+	start := time.Unix(1000, 0)
+	// 65530 .. 5 across the wrap, with 65533 and 2 lost
+	index := 0
+	for seq := uint16(65530); seq != 6; seq++ {
+		index++
+		if 65533 == seq || 2 == seq {
+			continue
+		}
+		// 20ms packets arriving on time
+		stats.update(newTestRtpPacket(false, seq, uint32(index*160), nil), start.Add(time.Duration(index)*20*time.Millisecond))
+	}
+	block := stats.reportBlock(start.Add(time.Second))
+	if 0x12345678 != block.SSRC || 65536+5 != block.HighestSequence || 2 != block.CumulativeLost {
+		t.Errorf("report block %+v", block)
+	}
+	if 2*256/12 != int(block.FractionLost) {
+		t.Errorf("fraction lost %d", block.FractionLost)
+	}
+	if 0 != block.Jitter {
+		t.Errorf("jitter %d", block.Jitter)
+	}
+
+	// nothing lost in the next interval
+	stats.update(newTestRtpPacket(false, 6, 13*160, nil), start.Add(13*20*time.Millisecond))
+	block = stats.reportBlock(start.Add(2 * time.Second))
+	if 0 != block.FractionLost || 2 != block.CumulativeLost {
+		t.Errorf("second report block %+v", block)
+	}
+}
+
+func TestReceptionStatsJitter(t *testing.T) {
+	stats := receptionStats{clockRate: 90000}
+	start := time.Unix(1000, 0)
+	for index := 0; index < 64; index++ {
+		arrival := start.Add(time.Duration(index) * 40 * time.Millisecond)
+		if 1 == index%2 {
+			arrival = arrival.Add(10 * time.Millisecond)
+		}
+		stats.update(newTestRtpPacket(false, uint16(index), uint32(index*3600), nil), arrival)
+	}
+	// the transit alternates by 900 units, the estimate converges to it
+	if jitter := stats.reportBlock(start).Jitter; jitter < 850 || 900 < jitter {
+		t.Errorf("jitter %d", jitter)
+	}
+}
+
+func TestReceptionStatsJitterLongRunning(t *testing.T) {
+	stats := receptionStats{clockRate: 90000}
+	// arrival time in clock units passes 2^63 since 1970, the timestamps 2^32
+	start := time.Unix(0, math.MaxInt64/90000).Add(-time.Second)
+	for index := 0; index < 50; index++ {
+		arrival := start.Add(time.Duration(index) * 40 * time.Millisecond)
+		stats.update(newTestRtpPacket(false, uint16(index), uint32(0xffffffff-20*3600)+uint32(index*3600), nil), arrival)
+	}
+	if jitter := stats.reportBlock(start).Jitter; 1 < jitter {
+		t.Errorf("jitter %d", jitter)
+	}
+}
+
+func TestReceptionStatsDelaySinceLastSR(t *testing.T) {
+	stats := receptionStats{}
+	arrival := time.Unix(1000, 0)
+	stats.update(newTestRtpPacket(false, 1, 0, nil), arrival)
+	stats.onSenderReport(&RtcpSenderReport{NTPTime: 0xe2d20e0080000000}, arrival)
+	block := stats.reportBlock(arrival.Add(1500 * time.Millisecond))
+	if 0x0e008000 != block.LastSR || 0x18000 != block.DelaySinceLastSR {
+		t.Errorf("report block %+v", block)
+	}
+}
+
+func TestMarshalRtcpReceiverReport(t *testing.T) {
+	blocks := []RtcpReportBlock{{
+		SSRC:             0x12345678,
+		FractionLost:     21,
+		CumulativeLost:   -3,
+		HighestSequence:  70000,
+		Jitter:           12,
+		LastSR:           0x0e008000,
+		DelaySinceLastSR: 0x18000,
+	}}
+	packet := marshalRtcpReceiverReport(0xcafebabe, "rtspclient@host", blocks)
+	if 0 != len(packet)%4 {
+		t.Fatalf("packet length %d", len(packet))
+	}
+	rtcp, err := ParseRtcp(packet)
+	if nil != err {
+		t.Fatal(err)
+	}
+	if 1 != len(rtcp.ReceiverReports) || 0xcafebabe != rtcp.ReceiverReports[0].SSRC || 1 != len(rtcp.ReceiverReports[0].Reports) {
+		t.Fatalf("receiver reports %+v", rtcp.ReceiverReports)
+	}
+	if blocks[0] != rtcp.ReceiverReports[0].Reports[0] {
+		t.Errorf("report block %+v", rtcp.ReceiverReports[0].Reports[0])
+	}
+	if 1 != len(rtcp.SourceDescs) || 1 != len(rtcp.SourceDescs[0].Items) || "rtspclient@host" != rtcp.SourceDescs[0].Items[0].Text {
+		t.Errorf("sdes %+v", rtcp.SourceDescs)
+	}
+}
+
+func TestRtcpInterval(t *testing.T) {
+	for index := 0; index < 100; index++ {
+		if interval := rtcpInterval(false); interval < 2*time.Second || 7*time.Second < interval {
+			t.Fatalf("interval %v", interval)
+		}
+		if interval := rtcpInterval(true); interval < time.Second || 4*time.Second < interval {
+			t.Fatalf("initial interval %v", interval)
+		}
+	}
+}
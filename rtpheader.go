@@ -0,0 +1,84 @@
+package rtspclient
+
+import (
+	"encoding/binary"
+	"errors"
+	"time"
+)
+
+const (
+	onvifReplayExtensionProfile = 0xabac
+	ntpUnixEpochOffset          = 2208988800 // seconds from 1900 to 1970
+)
+
+// OnvifReplayInfo is the ONVIF replay RTP header extension (profile 0xABAC)
+// sent by Profile G recorders.
+type OnvifReplayInfo struct {
+	Time          time.Time // absolute time of the access unit
+	CleanPoint    bool      // C: the access unit can be decoded on its own
+	End           bool      // E: last access unit of a contiguous section
+	Discontinuity bool      // D: gap in the recording before this access unit
+	CSeq          int       // low byte of the CSeq of the PLAY request
+}
+
+// stripRtpHeader removes the CSRC list, the header extension and the padding
+// of an RTP packet, so the payload starts behind a 12 byte fixed header. It
+// returns the ONVIF replay extension if the packet has one.
+func stripRtpHeader(src []byte) ([]byte, *OnvifReplayInfo, error) {
+	if len(src) < RtpHeaderLen {
+		return nil, nil, errors.New("rtp packet too short")
+	}
+	headerLen := RtpHeaderLen + 4*int(src[0]&0x0f)
+	if len(src) < headerLen {
+		return nil, nil, errors.New("rtp csrc list too long")
+	}
+	var replay *OnvifReplayInfo
+	if 0 != src[0]&0x10 {
+		if len(src) < headerLen+4 {
+			return nil, nil, errors.New("rtp header extension too short")
+		}
+		profile := binary.BigEndian.Uint16(src[headerLen:])
+		extensionLen := 4 * int(binary.BigEndian.Uint16(src[headerLen+2:]))
+		if len(src) < headerLen+4+extensionLen {
+			return nil, nil, errors.New("rtp header extension too short")
+		}
+		if onvifReplayExtensionProfile == profile && 12 <= extensionLen {
+			replay = parsingOnvifReplay(src[headerLen+4:])
+		}
+		headerLen += 4 + extensionLen
+	}
+	end := len(src)
+	if 0 != src[0]&0x20 {
+		padding := int(src[end-1])
+		if 0 == padding || end-headerLen < padding {
+			return nil, nil, errors.New("rtp padding too long")
+		}
+		end -= padding
+	}
+	if RtpHeaderLen == headerLen && len(src) == end {
+		return src, replay, nil
+	}
+
+	// move the fixed header up to the payload and clear P, X and CC
+	copy(src[headerLen-RtpHeaderLen:headerLen], src[:RtpHeaderLen])
+	src = src[headerLen-RtpHeaderLen : end]
+	src[0] &= 0xc0
+	return src, replay, nil
+}
+
+func parsingOnvifReplay(src []byte) *OnvifReplayInfo {
+	flags := src[8]
+	return &OnvifReplayInfo{
+		Time:          ntpTime(binary.BigEndian.Uint32(src), binary.BigEndian.Uint32(src[4:])),
+		CleanPoint:    0 != flags&0x80,
+		End:           0 != flags&0x40,
+		Discontinuity: 0 != flags&0x20,
+		CSeq:          int(src[9]),
+	}
+}
+
+// ntpTime converts a 64 bit NTP timestamp.
+func ntpTime(seconds uint32, fraction uint32) time.Time {
+	nanoseconds := (uint64(fraction) * 1000000000) >> 32
+	return time.Unix(int64(seconds)-ntpUnixEpochOffset, int64(nanoseconds)).UTC()
+}
@@ -0,0 +1,44 @@
+package rtspclient
+
+import (
+	"bytes"
+	"testing"
+	"time"
+)
+
+func TestOnvifReplayExtension(t *testing.T) {
+	packet := newTestRtpPacket(true, 1, 1000, nil)
+	// one CSRC, the replay extension and two bytes of padding
+	packet[0] |= 0x20 | 0x10 | 0x01
+	packet = append(packet, 0x11, 0x22, 0x33, 0x44)
+	packet = append(packet, 0xab, 0xac, 0x00, 0x03)
+	packet = append(packet, 0xe2, 0xd3, 0x4f, 0x80, 0x80, 0x00, 0x00, 0x00, 0xa0, 0x05, 0x00, 0x00)
+	packet = append(packet, 0x65, 0x88, 0x00, 0x02)
+
+	rtpParser := newRtpParser(MediaSubsession{CodecName: "H264", RtpTimestampFrequency: 90000})
+	frames := parsingTestPackets(rtpParser, packet)
+	if len(frames) != 1 || !bytes.Equal(frames[0].Data, []byte{0x65, 0x88}) {
+		t.Fatalf("payload corrupted: %x", frames[0].Data)
+	}
+	replay := frames[0].Replay
+	if nil == replay {
+		t.Fatal("replay extension not decoded")
+	}
+	if !replay.Time.Equal(time.Date(2020, 8, 4, 3, 7, 44, 500000000, time.UTC)) ||
+		!replay.CleanPoint || replay.End || !replay.Discontinuity || replay.CSeq != 5 {
+		t.Errorf("replay extension %+v", replay)
+	}
+}
+
+func TestStripRtpHeaderTruncated(t *testing.T) {
+	// 11 CSRCs announced in a 21 byte packet
+	packet := append(newTestRtpPacket(true, 1, 1000, nil), make([]byte, 9)...)
+	packet[0] |= 0x0b
+	if _, _, err := stripRtpHeader(packet); nil == err {
+		t.Errorf("csrc list beyond the packet accepted")
+	}
+	packet[0] |= 0x20
+	if _, _, err := stripRtpHeader(packet); nil == err {
+		t.Errorf("csrc list beyond the padded packet accepted")
+	}
+}
@@ -1,7 +1,10 @@
 package rtspclient
 
 import (
-	"log"
+	"encoding/binary"
+	"strings"
+	"sync/atomic"
+	"time"
 )
 
 const (
@@ -9,18 +12,41 @@ const (
 )
 
 type RtpParser struct {
-	payloadBuf       []byte
-	payloadLen       int
+	payloadBuf       []byte // grows up to maxPayloadLength
+	maxPayloadLength int
 	rtpSourceHandler IRtpParseInterface
+	depacketizer     IDepacketizer // registered depacketizer, replaces rtpSourceHandler
 	isMarkFrame      bool
+	replay           *OnvifReplayInfo
+	senderReport     atomic.Value // *RtcpSenderReport, the last one received
+	stats            receptionStats
+	clock            wallClock
+	ssrc             uint32 // atomic, of the last packet
+	reorder          *reorderBuffer
+	payloadFormat    int
+	rtxPayloadFormat int
+	nack             bool   // ask for missing packets
+	keyframeFir      bool   // request key frames with FIR instead of PLI
+	firSeq           uint32 // atomic
+	counters         *trackCounters
+	logger           Logger
+
+	injectParameterSets bool
 }
 
-func newRtpParser(codecName string) *RtpParser {
-	return &RtpParser{
-		payloadBuf:       make([]byte, MaxPayloadLength),
-		payloadLen:       0,
-		rtpSourceHandler: getRTPSourceHandler(codecName),
+func newRtpParser(media MediaSubsession) *RtpParser {
+	rtpParser := &RtpParser{
+		stats:    receptionStats{clockRate: media.RtpTimestampFrequency},
+		clock:    wallClock{clockRate: media.RtpTimestampFrequency},
+		counters: &trackCounters{},
+	}
+	if factory := lookupDepacketizer(media); nil != factory {
+		rtpParser.depacketizer = factory(media)
+		return rtpParser
 	}
+	rtpParser.maxPayloadLength = MaxPayloadLength
+	rtpParser.rtpSourceHandler = getRTPSourceHandler(media)
+	return rtpParser
 }
 
 func (rtpParser *RtpParser) splitRtpPacket(src []byte) ([]byte, []byte) {
@@ -36,44 +62,188 @@ func (rtpParser *RtpParser) pushData(header []byte, payload []byte) ([]byte, int
 	}
 	nalu := payload[naluHeaderSize:naluSize]
 
-	if rtpParser.payloadLen+len(nalu) > MaxPayloadLength {
-		log.Print("playload too long")
-		data := make([]byte, rtpParser.payloadLen)
-		copy(data, rtpParser.payloadBuf[:rtpParser.payloadLen])
+	if len(rtpParser.payloadBuf)+len(nalu) > rtpParser.maxPayloadLength {
+		rtpParser.getLogger().Warn("payload too long", "max", rtpParser.maxPayloadLength)
+		data := rtpParser.payloadBuf
 
-		rtpParser.payloadLen = 0
-		if len(nalu) < MaxPayloadLength {
-			copy(rtpParser.payloadBuf[rtpParser.payloadLen:], nalu)
-			rtpParser.payloadLen += len(nalu)
+		rtpParser.payloadBuf = nil
+		if len(nalu) < rtpParser.maxPayloadLength {
+			rtpParser.payloadBuf = append(rtpParser.payloadBuf, nalu...)
 		}
 		return data, naluSize
 	}
 
-	copy(rtpParser.payloadBuf[rtpParser.payloadLen:], nalu)
-	rtpParser.payloadLen += len(nalu)
+	rtpParser.payloadBuf = append(rtpParser.payloadBuf, nalu...)
 
 	if rtpParser.isMarkFrame {
-		data := make([]byte, rtpParser.payloadLen)
-		copy(data, rtpParser.payloadBuf[:rtpParser.payloadLen])
-		rtpParser.payloadLen = 0
+		data := rtpParser.payloadBuf
+		rtpParser.payloadBuf = rtpParser.payloadBuf[:0]
 		return data, naluSize
 	}
 	return nil, naluSize
 }
 
-func getRTPSourceHandler(codecName string) IRtpParseInterface {
-	switch codecName {
+// getLogger returns the logger of the track, the default one if none is set.
+func (rtpParser *RtpParser) getLogger() Logger {
+	if nil == rtpParser.logger {
+		return defaultLogger
+	}
+	return rtpParser.logger
+}
+
+// receivePacket runs an rtp packet through retransmission, statistics and
+// the reorder buffer. It returns the sequence numbers found missing.
+func (rtpParser *RtpParser) receivePacket(rtpData []byte, arrival time.Time, onFrame func(*RtspData)) []uint16 {
+	if len(rtpData) < RtpHeaderLen {
+		return nil
+	}
+	rtpParser.counters.onPacket(len(rtpData), arrival)
+	if 0 != rtpParser.rtxPayloadFormat && rtpParser.rtxPayloadFormat == int(rtpData[1]&0x7f) {
+		rtpData = rtpParser.unwrapRtx(rtpData)
+		if nil == rtpData {
+			return nil
+		}
+	} else {
+		rtpParser.stats.update(rtpData, arrival)
+		rtpParser.counters.onReception(&rtpParser.stats)
+		atomic.StoreUint32(&rtpParser.ssrc, binary.BigEndian.Uint32(rtpData[8:12]))
+	}
+
+	if nil == rtpParser.reorder {
+		rtpParser.parsingPacket(rtpData, onFrame)
+		return nil
+	}
+	return rtpParser.reorder.push(rtpData, arrival, func(packet []byte) {
+		rtpParser.parsingPacket(packet, onFrame)
+	})
+}
+
+// parsingPacket splits one RTP packet into frames and calls onFrame for every
+// frame the packet completes.
+func (rtpParser *RtpParser) parsingPacket(rtpData []byte, onFrame func(*RtspData)) {
+	rtpData, replay, err := stripRtpHeader(rtpData)
+	if nil != err {
+		rtpParser.getLogger().Warn("invalid rtp packet", "error", err)
+		return
+	}
+	if nil != replay {
+		rtpParser.replay = replay
+	}
+
+	if nil != rtpParser.depacketizer {
+		for _, rtspData := range rtpParser.depacketizer.Depacketize(newRtpPacket(rtpData)) {
+			if nil == rtspData.Replay {
+				rtspData.Replay = rtpParser.replay
+			}
+			onFrame(rtspData)
+		}
+		return
+	}
+	rtpParser.parsingFrames(rtpData, onFrame)
+}
+
+// parsingFrames runs the split/parse contract of rtpSourceHandler on a packet
+// with a 12 byte header.
+func (rtpParser *RtpParser) parsingFrames(rtpData []byte, onFrame func(*RtspData)) {
+	timestamp := binary.BigEndian.Uint32(rtpData[4:8])
+
+	totalLength := 0
+	header, payload := rtpParser.splitRtpPacket(rtpData)
+	for totalLength < len(payload) {
+		frame, completionLength := rtpParser.pushData(header, payload[totalLength:])
+		if 0 < len(frame) {
+			rtspData := newPooledRtspData(frame)
+			rtspData.Timestamp = timestamp
+			rtspData.Replay = rtpParser.replay
+			if frameInfo, ok := rtpParser.rtpSourceHandler.(IRtpFrameInfoInterface); ok {
+				frameInfo.FrameInfo(rtspData)
+			}
+			if prefixer, ok := rtpParser.rtpSourceHandler.(parameterSetsPrefixer); ok && rtpParser.injectParameterSets {
+				for _, nalu := range prefixer.parameterSetsPrefix(rtspData) {
+					prefixData := newPooledRtspData(nalu)
+					prefixData.Timestamp = timestamp
+					prefixData.Replay = rtspData.Replay
+					onFrame(prefixData)
+				}
+			}
+			onFrame(rtspData)
+		}
+		if completionLength <= 0 {
+			break
+		}
+		totalLength += completionLength
+	}
+}
+
+func getRTPSourceHandler(media MediaSubsession) IRtpParseInterface {
+	switch strings.ToUpper(media.CodecName) {
 	case "H264":
 		{
-			return &H264RtpParser{}
+			return newH264RtpParser(media)
 		}
 	case "H265", "HEVC":
 		{
-			return &HevcRtpParser{}
+			return newHevcRtpParser(media)
+		}
+	case "MPEG4-GENERIC":
+		{
+			return newAacRtpParser(media)
+		}
+	case "MP4A-LATM":
+		{
+			return newLatmRtpParser(media)
+		}
+	case "PCMU", "PCMA":
+		{
+			return newG711RtpParser(media)
+		}
+	case "G726-16", "G726-24", "G726-32", "G726-40", "AAL2-G726-16", "AAL2-G726-24", "AAL2-G726-32", "AAL2-G726-40":
+		{
+			return newG726RtpParser(media)
+		}
+	case "L16":
+		{
+			return newL16RtpParser(media)
+		}
+	case "OPUS":
+		{
+			return &OpusRtpParser{}
+		}
+	case "JPEG":
+		{
+			return newJpegRtpParser()
+		}
+	case "MP2T":
+		{
+			return newMp2tRtpParser()
+		}
+	case "VP8":
+		{
+			return &Vp8RtpParser{}
+		}
+	case "VP9":
+		{
+			return &Vp9RtpParser{}
+		}
+	case "AV1":
+		{
+			return &Av1RtpParser{}
+		}
+	case "MP4V-ES":
+		{
+			return newMp4vRtpParser(media)
+		}
+	case "H263-1998", "H263-2000":
+		{
+			return &H263RtpParser{}
+		}
+	case "VND.ONVIF.METADATA":
+		{
+			return &OnvifMetadataRtpParser{}
 		}
-	case "bbw":
+	case "SMPTE336M":
 		{
-			return &MarkRtpParser{}
+			return &KlvRtpParser{}
 		}
 	default:
 		{
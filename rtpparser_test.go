@@ -0,0 +1,68 @@
+package rtspclient
+
+import (
+	"testing"
+)
+
+func newTestFuaPackets(fragmentSize int, fragments int) [][]byte {
+	var packets [][]byte
+	fragment := make([]byte, fragmentSize)
+	for index := 0; index < fragments; index++ {
+		fuHeader := byte(0x05)
+		if 0 == index {
+			fuHeader |= 0x80
+		}
+		if fragments-1 == index {
+			fuHeader |= 0x40
+		}
+		payload := append([]byte{0x7c, fuHeader}, fragment...)
+		packets = append(packets, newTestRtpPacket(fragments-1 == index, uint16(index), 0, payload))
+	}
+	return packets
+}
+
+func TestRtpParserGrowableBuffer(t *testing.T) {
+	rtpParser := newRtpParser(MediaSubsession{CodecName: "H264", RtpTimestampFrequency: 90000})
+	if 0 != cap(rtpParser.payloadBuf) {
+		t.Fatalf("buffer of %d bytes allocated up front", cap(rtpParser.payloadBuf))
+	}
+
+	frames := parsingTestPackets(rtpParser, newTestFuaPackets(1000, 4)...)
+	if len(frames) != 1 || len(frames[0].Data) != 1+4*1000 {
+		t.Fatalf("fu-a frame not reassembled: %d frames", len(frames))
+	}
+	frames[0].Release()
+
+	// frames above the cap are flushed
+	rtpParser.maxPayloadLength = 2048
+	frames = parsingTestPackets(rtpParser, newTestFuaPackets(1000, 4)...)
+	for _, frame := range frames {
+		if len(frame.Data) > 2048 {
+			t.Errorf("frame of %d bytes above the cap", len(frame.Data))
+		}
+	}
+}
+
+func benchmarkRtpParser(b *testing.B, release bool) {
+	rtpParser := newRtpParser(MediaSubsession{CodecName: "H264", RtpTimestampFrequency: 90000})
+	packets := newTestFuaPackets(1400, 20)
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		for _, packet := range packets {
+			rtpParser.parsingPacket(packet, func(data *RtspData) {
+				if release {
+					data.Release()
+				}
+			})
+		}
+	}
+}
+
+func BenchmarkRtpParser(b *testing.B) {
+	benchmarkRtpParser(b, false)
+}
+
+func BenchmarkRtpParserRelease(b *testing.B) {
+	benchmarkRtpParser(b, true)
+}
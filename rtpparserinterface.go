@@ -5,6 +5,25 @@ type IRtpParseInterface interface {
 	ParsingRtp(header []byte, payload []byte) (naluHeaderSize int, naluSize int)
 }
 
+// IRtpFrameInfoInterface is implemented by parsers that know more about a
+// completed frame than the RTP packet carrying it, e.g. the timestamp of the
+// second access unit in an aggregated packet.
+type IRtpFrameInfoInterface interface {
+	FrameInfo(data *RtspData)
+}
+
+// parameterSetsPrefixer is implemented by parsers that can put the parameter
+// sets in front of a key frame.
+type parameterSetsPrefixer interface {
+	parameterSetsPrefix(data *RtspData) [][]byte
+}
+
+// videoInfoUpdater is implemented by parsers that decode the video size and
+// frame rate from the stream.
+type videoInfoUpdater interface {
+	updateVideoInfo(media *MediaSubsession) bool
+}
+
 const (
 	PacketHeaderLen = 4
 	RtpHeaderLen    = 12
@@ -3,10 +3,17 @@ package rtspclient
 import (
 	"bytes"
 	"encoding/binary"
-	"log"
 )
 
 type RTPStreamProtocol struct {
+	logger Logger
+}
+
+func (streamProtocol *RTPStreamProtocol) getLogger() Logger {
+	if nil == streamProtocol.logger {
+		return defaultLogger
+	}
+	return streamProtocol.logger
 }
 
 func (streamProtocol *RTPStreamProtocol) Init() {
@@ -31,8 +38,7 @@ func (streamProtocol *RTPStreamProtocol) UnserializeHeader(header []byte) (int,
 	} else if "rtsp" == string(header[0:4]) || "RTSP" == string(header[0:4]) {
 		return 0, []byte("\r\n\r\n")
 	} else {
-		log.Print("header error")
-		log.Print(header)
+		streamProtocol.getLogger().Warn("interleaved header error", "header", header)
 		return 0, nil
 	}
 }
@@ -49,7 +55,7 @@ func (streamProtocol *RTPStreamProtocol) GetContentLength(body []byte) int {
 
 	err := ParserRtspResponse(body, rtspContext)
 	if nil != err {
-		log.Print(err)
+		streamProtocol.getLogger().Warn("rtsp response error", "error", err)
 		return 0
 	}
 	return rtspContext.contentLength
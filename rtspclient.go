@@ -1,14 +1,14 @@
 package rtspclient
 
 import (
-	"bytes"
-	"encoding/binary"
 	"errors"
-	"log"
+	"fmt"
+	"math/rand"
 	"net"
 	"net/url"
-	"strconv"
 	"strings"
+	"sync"
+	"sync/atomic"
 	"time"
 
 	"github.com/NodeBoy2/rtspclient/tcpnetwork"
@@ -25,20 +25,46 @@ const (
 	RtspEventRequestError
 	// RtspEventDisconnected disconnected event
 	RtspEventDisconnected
+	// RtspEventMediaChanged the video description of RtpMediaMap[ChannelNum]
+	// changed mid-stream
+	RtspEventMediaChanged
+	// RtspEventRtcp an RTCP compound packet arrived for track ChannelNum
+	RtspEventRtcp
+	// RtspEventBye the sender of track ChannelNum ended its stream
+	RtspEventBye
+	// RtspEventStateChanged the session moved to State
+	RtspEventStateChanged
 )
 
 // RtspEvent rtsp session event
 type RtspEvent struct {
-	EventType int
-	Session   *RtspClientSession
-	Data      []byte // data
+	EventType  int
+	Session    *RtspClientSession
+	ChannelNum int           // rtp channel of track events
+	Rtcp       *RtcpCompound // packets of RtspEventRtcp and RtspEventBye
+	Err        error         // cause of RtspEventRequestError
+	State      SessionState  // new state of RtspEventStateChanged
+	Data       []byte        // data
 }
 
 // RtspData rtp data
 type RtspData struct {
-	ChannelNum int
-	Session    *RtspClientSession
-	Data       []byte
+	ChannelNum    int
+	Session       *RtspClientSession
+	Timestamp     uint32               // rtp timestamp of the frame
+	Time          time.Time            // wall-clock time of Timestamp, from RTCP sender reports once known
+	SampleRate    int                  // audio sampling rate, 0 if unknown
+	Samples       int                  // audio samples per channel in the frame, 0 if unknown
+	IsKeyFrame    bool                 // frame can be decoded on its own
+	MP2T          *MP2TStreamInfo      // elementary stream of a frame demuxed from MP2T
+	OnvifMetadata *OnvifMetadataStream // decoded document of an ONVIF metadata track
+	KLV           []KLVItem            // items of a KLV unit, they point into Data
+	MISB0601      *MISB0601            // UAS Datalink Local Set of a KLV unit, its Tags point into Data
+	Replay        *OnvifReplayInfo     // ONVIF replay extension, nil when playing live
+	SEI           *SEIInfo             // decoded messages of an H.264/H.265 SEI NAL unit
+	Data          []byte
+
+	buffer *[]byte // pooled memory of Data
 }
 
 func newRtspEvent(eventType int, session *RtspClientSession, data []byte) *RtspEvent {
@@ -59,42 +85,72 @@ func newRtspData(channelNum int, session *RtspClientSession, data []byte) *RtspD
 
 func (session *RtspClientSession) sendEvent(eventType int, data []byte) {
 	if nil == session.eventHandle {
-		log.Println("nil event queue")
+		session.logger.Debug("nil event handler", "event", eventType)
 		return
 	}
 	session.eventHandle(newRtspEvent(eventType, session, data))
 }
 
+func (session *RtspClientSession) sendErrorEvent(err error) {
+	if nil == session.eventHandle {
+		session.logger.Debug("nil event handler", "error", err)
+		return
+	}
+	event := newRtspEvent(RtspEventRequestError, session, nil)
+	event.Err = err
+	session.eventHandle(event)
+}
+
 type RtspClientSession struct {
-	username           string
-	password           string
-	address            string
-	rtspRequestInitial bool
-	timeoutSec         int
-	rtspContext        *RtspClientContext
-	dataHandle         func(*RtspData)
-	eventHandle        func(*RtspEvent)
-	rtpProtocol        *RTPStreamProtocol
-	tcpConn            *tcpnetwork.Connection
-	eventQueue         chan *tcpnetwork.ConnEvent
-	rtspResponseQueue  chan *RtspResponseContext
-	rtpChannelMap      map[int]*RtpParser
-	sdpInfo            *SDPInfo
-	RtpMediaMap        map[int]MediaSubsession
+	username            string
+	password            string
+	address             string
+	state               int32
+	stateMutex          sync.Mutex    // guards state changes, tcpConn and the track maps
+	requestLock         chan struct{} // held while a request waits for its reply
+	requestPending      int32
+	timeoutSec          int
+	rtspContext         *RtspClientContext
+	dataHandle          func(*RtspData)
+	metadataHandle      func(*RtspData)
+	eventHandle         func(*RtspEvent)
+	rtpProtocol         *RTPStreamProtocol
+	logger              Logger
+	tcpConn             *tcpnetwork.Connection
+	connDone            chan struct{} // closed when HandleConn of tcpConn quit
+	eventQueue          chan *tcpnetwork.ConnEvent
+	rtspResponseQueue   chan *RtspResponseContext
+	rtpChannelMap       map[int]*RtpParser // replaced under stateMutex, read it with channels
+	sdpInfo             *SDPInfo
+	maxFrameSize        int
+	injectParameterSets bool
+	rtcpSSRC            uint32
+	rtcpCname           string
+	rtcpTimer           *time.Timer  // receiver report timer, runs while rtp arrives
+	reorderTicker       *time.Ticker // expires held packets while reorder buffers run
+	reorderBufferSize   int
+	counters            *sessionCounters
+	lastRequest         []byte
+	lastRequestSent     time.Time
+	RtpMediaMap         map[int]MediaSubsession // replaced as tracks change, read it with GetMediaMap
 }
 
 func NewRtspClientSession(rtpHandler func(*RtspData), eventHandler func(*RtspEvent)) *RtspClientSession {
 	return &RtspClientSession{
-		dataHandle:         rtpHandler,
-		eventHandle:        eventHandler,
-		eventQueue:         make(chan *tcpnetwork.ConnEvent),
-		rtspResponseQueue:  make(chan *RtspResponseContext),
-		rtpProtocol:        &RTPStreamProtocol{},
-		rtspContext:        NewRtspClientContext(),
-		rtspRequestInitial: true,
-		timeoutSec:         2,
-		rtpChannelMap:      make(map[int]*RtpParser),
-		RtpMediaMap:        make(map[int]MediaSubsession),
+		dataHandle:        rtpHandler,
+		eventHandle:       eventHandler,
+		eventQueue:        make(chan *tcpnetwork.ConnEvent),
+		rtspResponseQueue: make(chan *RtspResponseContext, 1),
+		requestLock:       make(chan struct{}, 1),
+		rtpProtocol:       &RTPStreamProtocol{logger: defaultLogger},
+		logger:            defaultLogger,
+		rtspContext:       NewRtspClientContext(),
+		timeoutSec:        2,
+		rtpChannelMap:     make(map[int]*RtpParser),
+		rtcpSSRC:          rand.Uint32(),
+		rtcpCname:         rtcpCname(),
+		counters:          &sessionCounters{},
+		RtpMediaMap:       make(map[int]MediaSubsession),
 	}
 }
 
@@ -105,34 +161,60 @@ func (session *RtspClientSession) pushConnEvent(event *tcpnetwork.ConnEvent) {
 	session.eventQueue <- event
 }
 
+// HandleConn processes the events of the connection until it is closed, the
+// session is then closed and a new Play may start.
 func (session *RtspClientSession) HandleConn() {
+	done := session.connDone
 	defer func() {
-		close(session.eventQueue)
-		session.eventQueue = make(chan *tcpnetwork.ConnEvent)
+		// the held packets come first
+		session.expireReorderBuffers(time.Now(), true)
+		if nil != session.reorderTicker {
+			session.reorderTicker.Stop()
+			session.reorderTicker = nil
+		}
+		if nil != session.rtcpTimer {
+			session.rtcpTimer.Stop()
+			session.rtcpTimer = nil
+		}
+		session.setState(SessionStateClosing, sessionActiveStates...)
 		close(session.rtspResponseQueue)
-		session.rtspResponseQueue = make(chan *RtspResponseContext)
+		session.setState(SessionStateClosed, SessionStateClosing)
+		session.sendEvent(RtspEventDisconnected, nil)
+		close(done)
 	}()
 	for {
-		event, ok := <-session.eventQueue
-		if !ok {
-			// channel closed, quit
-			return
+		var rtcpTimeout, reorderTimeout <-chan time.Time
+		if nil != session.rtcpTimer {
+			rtcpTimeout = session.rtcpTimer.C
+		}
+		if nil != session.reorderTicker {
+			reorderTimeout = session.reorderTicker.C
+		}
+		var event *tcpnetwork.ConnEvent
+		var ok bool
+		select {
+		case <-rtcpTimeout:
+			session.sendReceiverReports()
+			session.rtcpTimer.Reset(rtcpInterval(false))
+			continue
+		case now := <-reorderTimeout:
+			session.expireReorderBuffers(now, false)
+			continue
+		case event, ok = <-session.eventQueue:
 		}
-		if nil == event {
+		if !ok || nil == event {
 			// channel closed, quit
-			event.Conn.Close()
 			return
 		}
 		switch event.EventType {
 		case tcpnetwork.ConnEventConnected:
 			{
-				log.Printf("conntion connected.")
+				session.logger.Info("connection connected", "address", session.address)
 			}
 			break
 		case tcpnetwork.ConnEventDisconnected:
 			{
-				log.Printf("conntion disconnected.")
-				session.sendEvent(RtspEventDisconnected, nil)
+				session.logger.Info("connection disconnected", "address", session.address)
 				return
 			}
 		case tcpnetwork.ConnEventData:
@@ -144,11 +226,11 @@ func (session *RtspClientSession) HandleConn() {
 			break
 		case tcpnetwork.ConnEventClose:
 			{
-				log.Printf("connection closed")
+				session.logger.Info("connection closed", "address", session.address)
 				return
 			}
 		default:
-			log.Printf("event type error.")
+			session.logger.Warn("connection event type error", "event", event.EventType)
 			break
 		}
 	}
@@ -166,51 +248,171 @@ func (session *RtspClientSession) parsingData(data []byte) {
 func (session *RtspClientSession) parsingRtp(data []byte) {
 	header := data[:4]
 	rtpData := data[4:]
-
-	timestampBufBefore := rtpData[4:8]
-	reader := bytes.NewReader(timestampBufBefore)
-	var timestamp uint32
-	err := binary.Read(reader, binary.BigEndian, &timestamp)
-	if nil != err {
-		return
-	}
+	arrival := time.Now()
 
 	// rtp data
 	channelNum := int(header[1])
-	rtpParser, ok := session.rtpChannelMap[channelNum]
+	if 1 == channelNum%2 {
+		// rtcp of the track on the previous channel
+		session.parsingRtcp(channelNum-1, rtpData)
+		return
+	}
+	rtpParser, ok := session.channels()[channelNum]
 	if ok {
-		totalLength := 0
-		header, payload := rtpParser.splitRtpPacket(rtpData)
-		for totalLength < len(payload) {
-			nalu, completionLength := rtpParser.pushData(header, payload[totalLength:])
-			if nil != nalu {
-				session.dataHandle(newRtspData(channelNum, session, nalu))
+		if nil == session.rtcpTimer {
+			// receiver reports start with the stream
+			session.rtcpTimer = time.NewTimer(rtcpInterval(true))
+		}
+		if nil != rtpParser.reorder && nil == session.reorderTicker {
+			session.reorderTicker = time.NewTicker(reorderMaxHold / 2)
+		}
+		missing := rtpParser.receivePacket(rtpData, arrival, session.frameHandler(channelNum, rtpParser, arrival))
+		session.sendNack(channelNum, rtpParser, missing)
+
+		if updater, ok := rtpParser.rtpSourceHandler.(videoInfoUpdater); ok {
+			media := session.GetMediaMap()[channelNum]
+			if updater.updateVideoInfo(&media) {
+				session.setMedia(channelNum, media)
+				event := newRtspEvent(RtspEventMediaChanged, session, nil)
+				event.ChannelNum = channelNum
+				if nil != session.eventHandle {
+					session.eventHandle(event)
+				}
 			}
-			totalLength += completionLength
 		}
 	}
 }
 
+// frameHandler returns the callback of the frames of a track completed at
+// arrival.
+func (session *RtspClientSession) frameHandler(channelNum int, rtpParser *RtpParser, arrival time.Time) func(*RtspData) {
+	return func(rtspData *RtspData) {
+		rtspData.ChannelNum = channelNum
+		rtspData.Session = session
+		rtspData.Time = rtpParser.clock.time(rtspData.Timestamp, arrival)
+		rtpParser.counters.onFrame(rtspData.IsKeyFrame, arrival)
+		if nil != session.metadataHandle && (nil != rtspData.OnvifMetadata || nil != rtspData.KLV) {
+			session.metadataHandle(rtspData)
+			return
+		}
+		session.dataHandle(rtspData)
+	}
+}
+
+// addTrack makes a track set up on an rtp channel visible. The maps of the
+// tracks are replaced, never changed, so a map once read stays valid.
+func (session *RtspClientSession) addTrack(channelNum int, rtpParser *RtpParser, media MediaSubsession) {
+	session.stateMutex.Lock()
+	channelMap := make(map[int]*RtpParser, len(session.rtpChannelMap)+1)
+	for index, track := range session.rtpChannelMap {
+		channelMap[index] = track
+	}
+	channelMap[channelNum] = rtpParser
+	session.rtpChannelMap = channelMap
+	session.stateMutex.Unlock()
+
+	session.setMedia(channelNum, media)
+	session.publishTracks()
+}
+
+// setMedia replaces the media of an rtp channel.
+func (session *RtspClientSession) setMedia(channelNum int, media MediaSubsession) {
+	session.stateMutex.Lock()
+	defer session.stateMutex.Unlock()
+	mediaMap := make(map[int]MediaSubsession, len(session.RtpMediaMap)+1)
+	for index, track := range session.RtpMediaMap {
+		mediaMap[index] = track
+	}
+	mediaMap[channelNum] = media
+	session.RtpMediaMap = mediaMap
+}
+
+// channels returns the tracks by rtp channel, the map must not be changed.
+func (session *RtspClientSession) channels() map[int]*RtpParser {
+	session.stateMutex.Lock()
+	defer session.stateMutex.Unlock()
+	return session.rtpChannelMap
+}
+
+// GetMediaMap returns the media of the tracks by rtp channel, it is safe to
+// call from any goroutine. The map must not be changed, a new one replaces it
+// when a track is set up or its video description changes.
+func (session *RtspClientSession) GetMediaMap() map[int]MediaSubsession {
+	session.stateMutex.Lock()
+	defer session.stateMutex.Unlock()
+	return session.RtpMediaMap
+}
+
+// SetLogger sets the logger of the session, nil discards all records. By
+// default warnings and errors go to the standard logger. RTSP requests and
+// responses are traced at debug level with credentials redacted. Set it
+// before Play.
+func (session *RtspClientSession) SetLogger(logger Logger) {
+	if nil == logger {
+		logger = discardLogger{}
+	}
+	session.logger = logger
+	session.rtpProtocol.logger = logger
+}
+
+// SetMetadataHandler sets the handler of decoded metadata frames, which then
+// no longer go to the data handler. Set it before Play.
+func (session *RtspClientSession) SetMetadataHandler(metadataHandler func(*RtspData)) {
+	session.metadataHandle = metadataHandler
+}
+
+// SetMaxFrameSize limits the frame buffer of every track, MaxPayloadLength by
+// default. Buffers grow up to it as needed. Set it before Play.
+func (session *RtspClientSession) SetMaxFrameSize(size int) {
+	session.maxFrameSize = size
+}
+
+// SetInjectParameterSets makes H.264 and H.265 tracks deliver the current
+// parameter sets, as frames with the same timestamp, in front of every IDR or
+// IRAP access unit that does not carry them. Set it before Play.
+func (session *RtspClientSession) SetInjectParameterSets(enable bool) {
+	session.injectParameterSets = enable
+}
+
+// GetRtpParseHandler returns the depacketizer of an rtp channel, e.g. to read
+// an in-band codec config. The depacketizer runs on the connection routine, so
+// use it from the data handler only.
+func (session *RtspClientSession) GetRtpParseHandler(channelNum int) IRtpParseInterface {
+	rtpParser, ok := session.channels()[channelNum]
+	if !ok {
+		return nil
+	}
+	if depacketizer, ok := rtpParser.depacketizer.(*parserDepacketizer); ok {
+		return depacketizer.rtpParser.rtpSourceHandler
+	}
+	return rtpParser.rtpSourceHandler
+}
+
 func (session *RtspClientSession) parsingRtsp(data []byte) {
-	log.Print(string(data))
+	session.logger.Debug("rtsp response", "message", redactCredentials(string(data)))
 	rtspResponseContext := &RtspResponseContext{}
 	err := ParserRtspResponse(data, rtspResponseContext)
 	if nil != err {
-		session.rtspResponseQueue <- nil
-		return
+		rtspResponseContext = nil
+	} else if 0 < len(rtspResponseContext.rtpInfos) {
+		// before handing over, rtp of the tracks follows right away
+		session.applyRtpInfo(rtspResponseContext.rtpInfos, time.Now())
+	}
+	select {
+	case session.rtspResponseQueue <- rtspResponseContext:
+	default:
+		// nobody waits, e.g. the reply of TEARDOWN
+		session.logger.Debug("unexpected rtsp response dropped")
 	}
-	session.rtspResponseQueue <- rtspResponseContext
 }
 
-func (session *RtspClientSession) sendRequest() error {
+func (session *RtspClientSession) sendRequest() (requestError error) {
 	var response *RtspResponseContext
 	var errorInfo error
 
-	sendRequestSuccess := false
-
 	defer func() {
-		if sendRequestSuccess == false {
-			session.sendEvent(RtspEventRequestError, nil)
+		if nil != requestError {
+			session.sendErrorEvent(requestError)
 		} else {
 			session.sendEvent(RtspEventRequestSuccess, nil)
 		}
@@ -221,7 +423,7 @@ func (session *RtspClientSession) sendRequest() error {
 
 	session.SendDescribe()
 
-	response, errorInfo = session.WaitRtspResponse()
+	response, errorInfo = session.waitResponse()
 	if nil != errorInfo {
 		return errorInfo
 	}
@@ -237,19 +439,23 @@ func (session *RtspClientSession) sendRequest() error {
 		}
 
 		session.SendDescribe()
-		response, errorInfo = session.WaitRtspResponse()
+		response, errorInfo = session.waitResponse()
 		if nil != errorInfo {
 			return errorInfo
 		}
 	}
 
-	if 200 != response.Status {
-		return errors.New("response error: " + strconv.Itoa(response.Status))
+	if errorInfo = checkResponse("DESCRIBE", response); nil != errorInfo {
+		return errorInfo
 	}
 
-	session.sdpInfo = parsingSDP(response.content)
-	if nil == session.sdpInfo {
-		return errors.New("parse sdp error")
+	session.sdpInfo, errorInfo = parsingSDP(response.content)
+	if nil != errorInfo {
+		session.logger.Warn("parse sdp error", "sdp", response.content, "error", errorInfo)
+		return errorInfo
+	}
+	if _, ok := session.setState(SessionStateDescribed, SessionStateConnecting); !ok {
+		return ErrSessionClosed
 	}
 
 	session.rtspContext.sessionID = ""
@@ -266,37 +472,52 @@ func (session *RtspClientSession) sendRequest() error {
 		}
 		rtpIndex := index * 2
 		rtcpIndex := index*2 + 1
-		session.rtpChannelMap[rtpIndex] = newRtpParser(media.CodecName)
-		session.RtpMediaMap[rtpIndex] = media
+		rtpParser := newRtpParser(media)
+		if 0 < session.maxFrameSize {
+			rtpParser.maxPayloadLength = session.maxFrameSize
+		}
+		rtpParser.injectParameterSets = session.injectParameterSets
+		rtpParser.setFeedback(media, session.reorderBufferSize)
+		rtpParser.logger = session.logger
+		rtpParser.counters.channelNum = rtpIndex
+		rtpParser.counters.codecName = media.CodecName
+		if updater, ok := rtpParser.rtpSourceHandler.(videoInfoUpdater); ok {
+			updater.updateVideoInfo(&media)
+		}
+		session.addTrack(rtpIndex, rtpParser, media)
 		session.SendTcpSetup(strTrackURL, rtpIndex, rtcpIndex)
 
-		response, errorInfo = session.WaitRtspResponse()
+		response, errorInfo = session.waitResponse()
 		if nil != errorInfo {
 			return errorInfo
 		}
-		if 200 != response.Status {
-			return errors.New("response error: " + strconv.Itoa(response.Status))
+		if errorInfo = checkResponse("SETUP", response); nil != errorInfo {
+			return errorInfo
 		}
 		session.rtspContext.sessionID = response.sessionID
 	}
+	if _, ok := session.setState(SessionStateReady, SessionStateDescribed); !ok {
+		return ErrSessionClosed
+	}
 
 	session.SendPlay(0, 1)
-	response, errorInfo = session.WaitRtspResponse()
+	response, errorInfo = session.waitResponse()
 	if nil != errorInfo {
 		return errorInfo
 	}
-	if 200 != response.Status {
-		return errors.New("response error: " + strconv.Itoa(response.Status))
+	if errorInfo = checkResponse("PLAY", response); nil != errorInfo {
+		return errorInfo
+	}
+	if _, ok := session.setState(SessionStatePlaying, SessionStateReady); !ok {
+		return ErrSessionClosed
 	}
-
-	sendRequestSuccess = true
 	return nil
 }
 
 func (session *RtspClientSession) ParsingURL(rtspURL string) error {
 	urlInfo, urlError := url.Parse(rtspURL)
 	if nil != urlError {
-		return errors.New("url parse error: " + rtspURL)
+		return fmt.Errorf("%w: %s", ErrInvalidURL, redactCredentials(rtspURL))
 	}
 
 	if "" != urlInfo.RawQuery {
@@ -317,86 +538,166 @@ func (session *RtspClientSession) ParsingURL(rtspURL string) error {
 	return nil
 }
 
+// SetConnection plays over conn, e.g. a tunnel, the url is set with
+// ParsingURL before.
 func (session *RtspClientSession) SetConnection(conn net.Conn) error {
-	session.tcpConn = tcpnetwork.NewConnection(conn, 0x0fff, session.pushConnEvent)
-	session.tcpConn.SetStreamProtocol(session.rtpProtocol)
-	session.tcpConn.Run()
+	if _, ok := session.setState(SessionStateConnecting, SessionStateInit, SessionStateClosed); !ok {
+		return ErrSessionConnected
+	}
+	return session.open(func() (net.Conn, error) {
+		return conn, nil
+	})
+}
+
+// open plays over the connection of dial, the session is connecting. A
+// failed session is closed on return.
+func (session *RtspClientSession) open(dial func() (net.Conn, error)) error {
+	if !session.lockRequest() {
+		session.setState(SessionStateClosed, SessionStateConnecting, SessionStateClosing)
+		return ErrRequestPending
+	}
+	conn, err := dial()
+	if nil != err {
+		session.unlockRequest()
+		session.setState(SessionStateClosed, SessionStateConnecting, SessionStateClosing)
+		return err
+	}
+	done, err := session.connect(conn)
+	session.unlockRequest()
+	if nil != err && nil != done {
+		session.Close()
+		<-done
+	}
+	return err
+}
+
+// connect runs conn and sets the session up, it returns the channel closed
+// when the connection is down once it runs.
+func (session *RtspClientSession) connect(conn net.Conn) (chan struct{}, error) {
+	atomic.AddUint64(&session.counters.connects, 1)
+	session.counters.url.Store(session.rtspContext.rtspURL)
+	tcpConn := tcpnetwork.NewConnection(conn, 0x0fff, session.pushConnEvent)
+	tcpConn.SetLogger(session.logger)
+	tcpConn.SetStreamProtocol(session.rtpProtocol)
+
+	session.stateMutex.Lock()
+	if SessionStateConnecting != session.State() {
+		// closed while dialing
+		session.stateMutex.Unlock()
+		conn.Close()
+		session.setState(SessionStateClosed, SessionStateClosing)
+		return nil, ErrSessionClosed
+	}
+	done := make(chan struct{})
+	session.tcpConn = tcpConn
+	session.connDone = done
+	session.eventQueue = make(chan *tcpnetwork.ConnEvent)
+	session.rtspResponseQueue = make(chan *RtspResponseContext, 1)
+	session.stateMutex.Unlock()
+
+	atomic.StoreInt32(&session.requestPending, 0)
+	tcpConn.Run()
 	go session.HandleConn()
 
-	return session.sendRequest()
+	return done, session.sendRequest()
 }
 
+// Close tears the session down and closes the connection, it may be called
+// from any goroutine, event handler included. The session is closed once the
+// connection is down, see RtspEventStateChanged.
 func (session *RtspClientSession) Close() {
-	if session.tcpConn.GetStatus() == tcpnetwork.ConnEventConnected {
-		session.SendTeardown()
-		session.WaitRtspResponse()
+	previous, ok := session.setState(SessionStateClosing, sessionActiveStates...)
+	if !ok {
+		return
 	}
-	session.tcpConn.Close()
+	tcpConn := session.connection()
+	if nil == tcpConn {
+		// still dialing, open finishes closing
+		return
+	}
+	if SessionStateReady <= previous && session.lockRequest() {
+		if tcpConn == session.connection() {
+			// the reply is not waited for
+			atomic.StoreInt32(&session.requestPending, 0)
+			session.SendTeardown()
+		}
+		session.unlockRequest()
+	}
+	tcpConn.Close()
 }
 
 func (session *RtspClientSession) PlayUseWebsocket(webURL string, rtspURL string) error {
-	urlError := session.ParsingURL(rtspURL)
-	if nil != urlError {
-		return errors.New("url parse error: " + rtspURL)
-	}
-
-	websocketConn, _, err := websocket.DefaultDialer.Dial(webURL, nil)
-	if nil != err {
-		session.sendEvent(RtspEventDisconnected, nil)
-		log.Println("connect error: ", webURL)
-		return err
+	if _, ok := session.setState(SessionStateConnecting, SessionStateInit, SessionStateClosed); !ok {
+		return ErrSessionConnected
 	}
+	return session.open(func() (net.Conn, error) {
+		urlError := session.ParsingURL(rtspURL)
+		if nil != urlError {
+			return nil, urlError
+		}
 
-	conn := &WebsocketConn{conn: websocketConn}
+		websocketConn, _, err := websocket.DefaultDialer.Dial(webURL, nil)
+		if nil != err {
+			session.sendEvent(RtspEventDisconnected, nil)
+			session.logger.Error("connect error", "url", redactCredentials(webURL), "error", err)
+			return nil, &ConnectError{Address: redactCredentials(webURL), Err: err}
+		}
 
-	session.SetConnection(conn)
-	return nil
+		return &WebsocketConn{conn: websocketConn}, nil
+	})
 }
 
+// Play connects to rtspURL and plays all tracks, it fails with
+// ErrSessionConnected unless the session is new or closed.
 func (session *RtspClientSession) Play(rtspURL string) error {
-
-	if nil != session.tcpConn && tcpnetwork.ConnStatusConnected == session.tcpConn.GetStatus() {
-		return errors.New("session is connected")
+	if _, ok := session.setState(SessionStateConnecting, SessionStateInit, SessionStateClosed); !ok {
+		return ErrSessionConnected
 	}
+	return session.open(func() (net.Conn, error) {
+		urlError := session.ParsingURL(rtspURL)
+		if nil != urlError {
+			return nil, urlError
+		}
 
-	urlError := session.ParsingURL(rtspURL)
-	if nil != urlError {
-		return errors.New("url parse error: " + rtspURL)
-	}
+		// create connection
+		conn, err := net.DialTimeout("tcp", session.address, time.Duration(session.timeoutSec)*time.Second)
+		if nil != err {
+			session.sendEvent(RtspEventDisconnected, nil)
+			session.logger.Error("connect error", "address", session.address, "error", err)
+			return nil, &ConnectError{Address: session.address, Err: err}
+		}
+		return conn, nil
+	})
+}
 
-	// create connection
-	conn, err := net.DialTimeout("tcp", session.address, time.Duration(session.timeoutSec)*time.Second)
-	if nil != err {
-		session.sendEvent(RtspEventDisconnected, nil)
-		println(err.Error())
-		println(session.address)
-		return errors.New("connect " + session.address + "")
+// WaitRtspResponse waits for the reply of the last request, a timeout closes
+// the session.
+func (session *RtspClientSession) WaitRtspResponse() (*RtspResponseContext, error) {
+	response, err := session.waitResponse()
+	if errors.Is(err, ErrTimeout) {
+		session.Close()
 	}
-
-	return session.SetConnection(conn)
+	return response, err
 }
 
-func (session *RtspClientSession) WaitRtspResponse() (*RtspResponseContext, error) {
-	defer func() {
-		session.rtspRequestInitial = true
-	}()
+func (session *RtspClientSession) waitResponse() (*RtspResponseContext, error) {
+	defer atomic.StoreInt32(&session.requestPending, 0)
 
 	select {
 	case response, ok := <-session.rtspResponseQueue:
 		{
 			if !ok {
-				return nil, errors.New("rtsp connection disconnect")
+				return nil, ErrDisconnected
 			}
 			if nil == response {
-				return nil, errors.New("parsing rtsp response error")
+				return nil, ErrInvalidResponse
 			}
+			session.recordRequestLatency(session.lastRequest, time.Since(session.lastRequestSent))
 			return response, nil
 		}
 	case <-time.After(time.Duration(session.timeoutSec) * time.Second):
 		{
-			session.Close()
-			return nil, errors.New("recv response time out")
+			return nil, ErrTimeout
 		}
 	}
-
 }
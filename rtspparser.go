@@ -14,6 +14,7 @@ type RtspResponseContext struct {
 	sessionID           string
 	basicAuthenticator  *Authenticator
 	digestAuthenticator *Authenticator
+	rtpInfos            []RtpInfo
 }
 
 const (
@@ -51,6 +52,11 @@ func ParserRtspResponse(response []byte, context *RtspResponseContext) error {
 
 	fields := strings.Split(string(rtspResponse), "\r\n")
 	for _, field := range fields {
+		if colon := strings.Index(field, ":"); 0 < colon && strings.EqualFold(strings.TrimSpace(field[:colon]), sRTPInfoHeader) {
+			// urls contain colons
+			context.rtpInfos = parsingRtpInfo(field[colon+1:])
+			continue
+		}
 		keyValue := strings.Split(field, ":")
 		if 2 != len(keyValue) {
 			continue
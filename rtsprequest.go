@@ -1,9 +1,9 @@
 package rtspclient
 
 import (
-	"errors"
 	"fmt"
-	"log"
+	"sync/atomic"
+	"time"
 )
 
 const (
@@ -19,6 +19,7 @@ type RtspClientContext struct {
 	setupHeaders string
 	cseq         int
 	bandwidth    int //bps
+	onvifReplay  bool
 	authenicator *Authenticator
 }
 
@@ -30,15 +31,18 @@ func NewRtspClientContext() *RtspClientContext {
 }
 
 func (session *RtspClientSession) sendRequst(request []byte) {
-	log.Println(string(request))
-	session.rtspRequestInitial = false
-	session.tcpConn.Send([]byte(request), false)
+	session.logger.Debug("rtsp request", "message", redactCredentials(string(request)))
+	session.lastRequest = request
+	session.lastRequestSent = time.Now()
+	if tcpConn := session.connection(); nil != tcpConn {
+		tcpConn.Send([]byte(request), false)
+	}
 	session.rtspContext.cseq++
 }
 
 func (session *RtspClientSession) SendDescribe() error {
-	if !session.rtspRequestInitial {
-		return errors.New("waiting last request Reply")
+	if !atomic.CompareAndSwapInt32(&session.requestPending, 0, 1) {
+		return ErrRequestPending
 	}
 	request := fmt.Sprintf(("DESCRIBE %s RTSP/1.0\r\n" +
 		"CSeq: %d\r\n" +
@@ -57,8 +61,8 @@ func (session *RtspClientSession) SendDescribe() error {
 }
 
 func (session *RtspClientSession) SendTcpSetup(inTrackURL string, inClientRTPid int, inClientRTCPid int) error {
-	if !session.rtspRequestInitial {
-		return errors.New("waiting last request Reply")
+	if !atomic.CompareAndSwapInt32(&session.requestPending, 0, 1) {
+		return ErrRequestPending
 	}
 
 	request := fmt.Sprintf(("SETUP %s RTSP/1.0\r\n" +
@@ -81,8 +85,8 @@ func (session *RtspClientSession) SendTcpSetup(inTrackURL string, inClientRTPid
 }
 
 func (session *RtspClientSession) SendPause() error {
-	if !session.rtspRequestInitial {
-		return errors.New("waiting last request Reply")
+	if !atomic.CompareAndSwapInt32(&session.requestPending, 0, 1) {
+		return ErrRequestPending
 	}
 
 	request := fmt.Sprintf(("PAUSE %s RTSP/1.0\r\n" +
@@ -95,9 +99,15 @@ func (session *RtspClientSession) SendPause() error {
 	return nil
 }
 
+// SetOnvifReplay adds "Require: onvif-replay" to PLAY requests, asking an
+// ONVIF recorder for the replay header extension.
+func (session *RtspClientSession) SetOnvifReplay(enable bool) {
+	session.rtspContext.onvifReplay = enable
+}
+
 func (session *RtspClientSession) SendPlay(inStartTimeSec int, inSpeed int) error {
-	if !session.rtspRequestInitial {
-		return errors.New("waiting last request Reply")
+	if !atomic.CompareAndSwapInt32(&session.requestPending, 0, 1) {
+		return ErrRequestPending
 	}
 
 	var strSpeed string
@@ -107,7 +117,7 @@ func (session *RtspClientSession) SendPlay(inStartTimeSec int, inSpeed int) erro
 
 	var strStartTime string
 	if inStartTimeSec != 0 {
-		strSpeed = fmt.Sprintf("Range: npt=%d.0-\r\n", float32(inStartTimeSec))
+		strStartTime = fmt.Sprintf("Range: npt=%d.0-\r\n", inStartTimeSec)
 	}
 
 	request := fmt.Sprintf(("PLAY %s RTSP/1.0\r\n" +
@@ -118,6 +128,9 @@ func (session *RtspClientSession) SendPlay(inStartTimeSec int, inSpeed int) erro
 		"x-prebuffer: maxtime=3.0\r\n" +
 		"User-agent: %s\r\n"), session.rtspContext.rtspURL, session.rtspContext.cseq, session.rtspContext.sessionID, strStartTime, strSpeed, session.rtspContext.userAgent)
 
+	if session.rtspContext.onvifReplay {
+		request += "Require: onvif-replay\r\n"
+	}
 	if 0 != session.rtspContext.bandwidth {
 		request += fmt.Sprintf("Bandwidth: %d\r\n", session.rtspContext.bandwidth)
 	}
@@ -131,8 +144,8 @@ func (session *RtspClientSession) SendPlay(inStartTimeSec int, inSpeed int) erro
 }
 
 func (session *RtspClientSession) SendTeardown() error {
-	if !session.rtspRequestInitial {
-		return errors.New("waiting last request Reply")
+	if !atomic.CompareAndSwapInt32(&session.requestPending, 0, 1) {
+		return ErrRequestPending
 	}
 
 	request := fmt.Sprintf(("TEARDOWN %s RTSP/1.0\r\n" +
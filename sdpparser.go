@@ -2,7 +2,6 @@ package rtspclient
 
 import (
 	"fmt"
-	"log"
 	"strconv"
 	"strings"
 
@@ -19,7 +18,11 @@ type MediaSubsession struct {
 	VideoFramerate        int // "a=framerate: <fps>" or "a=x-framerate: <fps>"
 	VideoWidth            int // "a=x-dimensions:<width>,<height>"
 	VideoHeight           int
-	Fmtp                  map[string]string
+	VideoSPS              *SPSInfo          // decoded from the H.264/H.265 parameter sets
+	Fmtp                  map[string]string // "a=fmtp:" parameters of the format, names lower cased
+	RtcpFeedback          []string          // "a=rtcp-fb:" values of the format, e.g. "nack", "nack pli", "ccm fir"
+	RtxPayloadFormat      int               // RFC 4588 retransmission format, 0 if none
+	RtxTime               int               // "rtx-time" of the retransmission format in ms
 }
 
 type SDPInfo struct {
@@ -31,38 +34,40 @@ func parsingSDPLine(strSdp string) (*sdp.Message, error) {
 	var err error
 	sdpSession, err = sdp.DecodeSession([]byte(strSdp), sdpSession)
 	if nil != err {
-		log.Fatal("sdp decode session error: ", err)
 		return nil, err
 	}
 	sdpDecoder := sdp.NewDecoder(sdpSession)
 	sdpMessage := &sdp.Message{}
 	err = sdpDecoder.Decode(sdpMessage)
 	if nil != err {
-		log.Fatal("sdp decode error: ", err)
 		return nil, err
 	}
 	return sdpMessage, nil
 }
 
-func parsingSDP(sdp string) *SDPInfo {
+func parsingSDP(sdp string) (*SDPInfo, error) {
 
 	sdpMessage, errorInfo := parsingSDPLine(sdp)
 	if nil != errorInfo {
-		return nil
+		return nil, fmt.Errorf("%w: %v", ErrSDPInvalid, errorInfo)
 	}
 
 	if 0 >= len(sdpMessage.Medias) {
-		return nil
+		return nil, fmt.Errorf("%w: no media", ErrSDPInvalid)
 	}
 
 	sdpInfo := &SDPInfo{Medias: make([]MediaSubsession, len(sdpMessage.Medias))}
 
 	for index, meidaInfo := range sdpMessage.Medias {
 		sdpInfo.Medias[index].MediumName = meidaInfo.Description.Type
-		sdpInfo.Medias[index].PayloadFormat, _ = strconv.Atoi(meidaInfo.Description.Format)
-		codecName, _, timestampFrequency, channels := getPayloadInfo(sdpInfo.Medias[index].PayloadFormat)
+		// the first format is the media, later ones e.g. its retransmissions
+		if formats := strings.Fields(meidaInfo.Description.Format); 0 < len(formats) {
+			sdpInfo.Medias[index].PayloadFormat, _ = strconv.Atoi(formats[0])
+		}
+		payloadFormat := sdpInfo.Medias[index].PayloadFormat
+		codecName, _, timestampFrequency, channels := getPayloadInfo(payloadFormat)
 		if "" == codecName {
-			sdpRtpmap := meidaInfo.Attributes.Value("rtpmap")
+			sdpRtpmap := getFormatAttribute(meidaInfo.Attributes.Values("rtpmap"), payloadFormat)
 			if "" != sdpRtpmap {
 				codecName, timestampFrequency, channels = getPayloadInfoForRtpmap(sdpRtpmap)
 			}
@@ -88,15 +93,78 @@ func parsingSDP(sdp string) *SDPInfo {
 			sdpInfo.Medias[index].VideoFramerate = getFramerateForFramerate(sdpFramerate)
 		}
 
-		sdpFmtp := meidaInfo.Attributes.Value("fmtp")
+		sdpFmtp := getFormatAttribute(meidaInfo.Attributes.Values("fmtp"), payloadFormat)
 		if "" != sdpFmtp {
 			sdpInfo.Medias[index].Fmtp = getFmtParame(sdpFmtp)
-			log.Print(sdpFmtp)
 		}
 
+		sdpInfo.Medias[index].RtcpFeedback = getRtcpFeedback(meidaInfo.Attributes.Values("rtcp-fb"), payloadFormat)
+		sdpInfo.Medias[index].RtxPayloadFormat, sdpInfo.Medias[index].RtxTime = getRtxFormat(meidaInfo.Attributes, payloadFormat)
+
+	}
+
+	return sdpInfo, nil
+}
+
+// getFormatAttribute returns the "<format> <value>" attribute of a payload
+// format, else the first one that names no other format.
+func getFormatAttribute(values []string, payloadFormat int) string {
+	strFormat := strconv.Itoa(payloadFormat)
+	fallback := ""
+	for _, value := range values {
+		fields := strings.Fields(value)
+		if 0 < len(fields) && strFormat == fields[0] {
+			return value
+		}
+		if "" == fallback {
+			if 0 == len(fields) {
+				fallback = value
+			} else if _, err := strconv.Atoi(fields[0]); nil != err {
+				fallback = value
+			}
+		}
+	}
+	if "" == fallback && 1 == len(values) {
+		// a lone attribute with a mismatched format
+		return values[0]
 	}
+	return fallback
+}
 
-	return sdpInfo
+func getRtcpFeedback(values []string, payloadFormat int) []string {
+	// "a=rtcp-fb:<format> <type> [<subtype>]", "*" for all formats
+	strFormat := strconv.Itoa(payloadFormat)
+	var feedback []string
+	for _, value := range values {
+		fields := strings.Fields(strings.ToLower(value))
+		if 2 <= len(fields) && (strFormat == fields[0] || "*" == fields[0]) {
+			feedback = append(feedback, strings.Join(fields[1:], " "))
+		}
+	}
+	return feedback
+}
+
+func getRtxFormat(attributes sdp.Attributes, payloadFormat int) (int, int) {
+	// "a=rtpmap:<rtx format> rtx/<freq>" with "a=fmtp:<rtx format> apt=<format>"
+	for _, rtpmap := range attributes.Values("rtpmap") {
+		codecName, _, _ := getPayloadInfoForRtpmap(rtpmap)
+		if !strings.EqualFold("rtx", codecName) {
+			continue
+		}
+		var rtxFormat int
+		fmt.Sscanf(rtpmap, "%d", &rtxFormat)
+		for _, fmtp := range attributes.Values("fmtp") {
+			if fields := strings.Fields(fmtp); 0 == len(fields) || strconv.Itoa(rtxFormat) != fields[0] {
+				continue
+			}
+			parame := getFmtParame(fmtp)
+			if strconv.Itoa(payloadFormat) == parame["apt"] {
+				rtxTime, _ := strconv.Atoi(parame["rtx-time"])
+				return rtxFormat, rtxTime
+			}
+		}
+	}
+	return 0, 0
 }
 
 func getFramerateForFramerate(sdpFramerate string) int {
@@ -106,19 +174,24 @@ func getFramerateForFramerate(sdpFramerate string) int {
 }
 
 func getFmtParame(sdpFmtp string) map[string]string {
+	// "a=fmtp:<format> <name>=<value>;<name>=<value>..."
+	// Parameter names are case-insensitive, so they are stored lower case.
 	fmtParame := make(map[string]string)
 
-	fieldsFmtp := strings.Split(sdpFmtp, " ")
-	for _, field := range fieldsFmtp {
-		fields := strings.Split(field, " ")
-		for _, fieldTemp := range fields {
-			fieldTemp = strings.Replace(fieldTemp, ";", "", -1)
-			index := strings.Index(fieldTemp, "=")
-			if index == -1 {
-				fmtParame[fieldTemp] = ""
-			} else {
-				fmtParame[fieldTemp[:index]] = field[index+1:]
-			}
+	sdpFmtp = strings.TrimSpace(sdpFmtp)
+	if index := strings.IndexAny(sdpFmtp, " \t"); index != -1 {
+		sdpFmtp = sdpFmtp[index+1:]
+	}
+	for _, field := range strings.Split(sdpFmtp, ";") {
+		field = strings.TrimSpace(field)
+		if "" == field {
+			continue
+		}
+		index := strings.Index(field, "=")
+		if index == -1 {
+			fmtParame[strings.ToLower(field)] = ""
+		} else {
+			fmtParame[strings.ToLower(strings.TrimSpace(field[:index]))] = strings.TrimSpace(field[index+1:])
 		}
 	}
 	return fmtParame
@@ -201,3 +274,25 @@ func getPayloadInfo(payloadFormat int) (string, string, int, int) {
 	}
 	return "", "", 0, 0
 }
+
+// fmtpInt returns the integer value of an fmtp parameter, or def if it is
+// missing or not a number.
+func (media MediaSubsession) fmtpInt(name string, def int) int {
+	value, ok := media.Fmtp[name]
+	if !ok {
+		return def
+	}
+	number, err := strconv.Atoi(value)
+	if nil != err {
+		return def
+	}
+	return number
+}
+
+// channelCount returns the number of audio channels, 1 when not signalled.
+func (media MediaSubsession) channelCount() int {
+	if media.Channels < 1 {
+		return 1
+	}
+	return media.Channels
+}
@@ -0,0 +1,259 @@
+package rtspclient
+
+import (
+	"errors"
+	"sync"
+)
+
+// SEI payload types decoded by ParseH264SEI and ParseH265SEI.
+const (
+	SEIPayloadPictureTiming        = 1
+	SEIPayloadUserDataUnregistered = 5
+	SEIPayloadTimeCode             = 136 // H.265 only
+)
+
+const (
+	h264NalSEI       = 6
+	hevcNalPrefixSEI = 39
+	hevcNalSuffixSEI = 40
+)
+
+// SEIInfo holds the SEI messages of an SEI NAL unit.
+type SEIInfo struct {
+	Messages      []SEIMessage
+	PictureTiming *SEIPictureTiming // picture timing or H.265 time code
+	UserData      []SEIUserData     // user_data_unregistered messages
+}
+
+// SEIMessage is a raw SEI message, emulation prevention removed.
+type SEIMessage struct {
+	PayloadType int
+	Payload     []byte
+}
+
+// SEIPictureTiming is a picture timing SEI message. Fields the stream does not
+// carry are -1; H.265 time code messages only fill ClockTimestamps.
+type SEIPictureTiming struct {
+	CpbRemovalDelay int
+	DpbOutputDelay  int
+	PicStruct       int
+	ClockTimestamps []SEIClockTimestamp
+}
+
+// SEIClockTimestamp is a clock timestamp of a picture timing or time code
+// message. Seconds, Minutes and Hours are -1 if absent.
+type SEIClockTimestamp struct {
+	CtType         int // H.264 only
+	NuitFieldBased bool
+	CountingType   int
+	Discontinuity  bool
+	CntDropped     bool
+	NFrames        int
+	Seconds        int
+	Minutes        int
+	Hours          int
+	TimeOffset     int
+}
+
+// SEIUserData is a user_data_unregistered message. Decoded is the result of
+// the decoder registered for its UUID, if any.
+type SEIUserData struct {
+	UUID    [16]byte
+	Data    []byte
+	Decoded interface{}
+}
+
+// SEIUserDataDecoder decodes the payload of a vendor's user data, without
+// the UUID.
+type SEIUserDataDecoder func(data []byte) (interface{}, error)
+
+var (
+	seiUserDataDecoders     = make(map[[16]byte]SEIUserDataDecoder)
+	seiUserDataDecodersLock sync.RWMutex
+)
+
+// RegisterSEIUserDataDecoder decodes the user_data_unregistered messages with
+// the given UUID into SEIUserData.Decoded.
+func RegisterSEIUserDataDecoder(uuid [16]byte, decoder SEIUserDataDecoder) {
+	seiUserDataDecodersLock.Lock()
+	defer seiUserDataDecodersLock.Unlock()
+	seiUserDataDecoders[uuid] = decoder
+}
+
+func lookupSEIUserDataDecoder(uuid [16]byte) SEIUserDataDecoder {
+	seiUserDataDecodersLock.RLock()
+	defer seiUserDataDecodersLock.RUnlock()
+	return seiUserDataDecoders[uuid]
+}
+
+var errSEIInvalid = errors.New("sei: invalid message")
+
+// ParseH264SEI decodes an H.264 SEI NAL unit. sps is the active SPS, which
+// picture timing depends on; without it picture timing is not decoded.
+func ParseH264SEI(nalu []byte, sps *SPSInfo) (*SEIInfo, error) {
+	if len(nalu) < 2 || h264NalSEI != nalu[0]&0x1f {
+		return nil, errSEIInvalid
+	}
+	return parsingSEI(unescapeRBSP(nalu[1:]), sps, false)
+}
+
+// ParseH265SEI decodes an H.265 prefix or suffix SEI NAL unit.
+func ParseH265SEI(nalu []byte, sps *SPSInfo) (*SEIInfo, error) {
+	if len(nalu) < 3 {
+		return nil, errSEIInvalid
+	}
+	if nalType := int(nalu[0]>>1) & 0x3f; hevcNalPrefixSEI != nalType && hevcNalSuffixSEI != nalType {
+		return nil, errSEIInvalid
+	}
+	return parsingSEI(unescapeRBSP(nalu[2:]), sps, true)
+}
+
+func parsingSEI(rbsp []byte, sps *SPSInfo, hevc bool) (*SEIInfo, error) {
+	info := &SEIInfo{}
+	// stop at the rbsp trailing bits
+	for 1 < len(rbsp) || (1 == len(rbsp) && 0x80 != rbsp[0]) {
+		payloadType, n := readSEIValue(rbsp)
+		rbsp = rbsp[n:]
+		payloadSize, n := readSEIValue(rbsp)
+		rbsp = rbsp[n:]
+		if n == 0 || len(rbsp) < payloadSize {
+			return info, errSEIInvalid
+		}
+		payload := rbsp[:payloadSize]
+		rbsp = rbsp[payloadSize:]
+		info.Messages = append(info.Messages, SEIMessage{PayloadType: payloadType, Payload: payload})
+
+		switch payloadType {
+		case SEIPayloadPictureTiming:
+			if nil != sps {
+				info.PictureTiming = parsingSEIPictureTiming(payload, sps, hevc)
+			}
+		case SEIPayloadUserDataUnregistered:
+			if 16 <= len(payload) {
+				userData := SEIUserData{Data: payload[16:]}
+				copy(userData.UUID[:], payload[:16])
+				if decoder := lookupSEIUserDataDecoder(userData.UUID); nil != decoder {
+					userData.Decoded, _ = decoder(userData.Data)
+				}
+				info.UserData = append(info.UserData, userData)
+			}
+		case SEIPayloadTimeCode:
+			if hevc {
+				info.PictureTiming = parsingSEITimeCode(payload)
+			}
+		}
+	}
+	return info, nil
+}
+
+// readSEIValue reads a payload type or size coded as a run of 0xff bytes.
+func readSEIValue(src []byte) (int, int) {
+	value := 0
+	for index, b := range src {
+		value += int(b)
+		if 0xff != b {
+			return value, index + 1
+		}
+	}
+	return 0, 0
+}
+
+var h264NumClockTS = []int{1, 1, 1, 2, 2, 3, 3, 2, 3}
+
+func parsingSEIPictureTiming(payload []byte, sps *SPSInfo, hevc bool) *SEIPictureTiming {
+	reader := newSPSReader(payload)
+	timing := &SEIPictureTiming{CpbRemovalDelay: -1, DpbOutputDelay: -1, PicStruct: -1}
+	params := &sps.picTiming
+
+	if hevc {
+		// the HRD delays follow pic_struct and are not decoded
+		if params.picStructPresent {
+			timing.PicStruct = reader.u(4)
+		}
+		if nil != reader.err {
+			return nil
+		}
+		return timing
+	}
+
+	if params.cpbDpbDelaysPresent {
+		timing.CpbRemovalDelay = reader.u(params.cpbRemovalDelayLength)
+		timing.DpbOutputDelay = reader.u(params.dpbOutputDelayLength)
+	}
+	if params.picStructPresent {
+		timing.PicStruct = reader.u(4)
+		if timing.PicStruct < len(h264NumClockTS) {
+			for index := 0; index < h264NumClockTS[timing.PicStruct]; index++ {
+				if !reader.flag() {
+					continue
+				}
+				clockTimestamp := SEIClockTimestamp{CtType: reader.u(2)}
+				parsingSEIClockTimestamp(reader, &clockTimestamp, 8)
+				if 0 < params.timeOffsetLength {
+					clockTimestamp.TimeOffset = signExtend(reader.u(params.timeOffsetLength), params.timeOffsetLength)
+				}
+				timing.ClockTimestamps = append(timing.ClockTimestamps, clockTimestamp)
+			}
+		}
+	}
+	if nil != reader.err {
+		return nil
+	}
+	return timing
+}
+
+func parsingSEITimeCode(payload []byte) *SEIPictureTiming {
+	reader := newSPSReader(payload)
+	timing := &SEIPictureTiming{CpbRemovalDelay: -1, DpbOutputDelay: -1, PicStruct: -1}
+	numClockTS := reader.u(2)
+	for index := 0; index < numClockTS; index++ {
+		if !reader.flag() {
+			continue
+		}
+		clockTimestamp := SEIClockTimestamp{CtType: -1}
+		parsingSEIClockTimestamp(reader, &clockTimestamp, 9)
+		timeOffsetLength := reader.u(5)
+		if 0 < timeOffsetLength {
+			clockTimestamp.TimeOffset = signExtend(reader.u(timeOffsetLength), timeOffsetLength)
+		}
+		timing.ClockTimestamps = append(timing.ClockTimestamps, clockTimestamp)
+	}
+	if nil != reader.err {
+		return nil
+	}
+	return timing
+}
+
+// parsingSEIClockTimestamp reads the clock timestamp fields from
+// nuit_field_based_flag to the hours.
+func parsingSEIClockTimestamp(reader *spsReader, clockTimestamp *SEIClockTimestamp, nFramesLength int) {
+	clockTimestamp.NuitFieldBased = reader.flag()
+	clockTimestamp.CountingType = reader.u(5)
+	fullTimestamp := reader.flag()
+	clockTimestamp.Discontinuity = reader.flag()
+	clockTimestamp.CntDropped = reader.flag()
+	clockTimestamp.NFrames = reader.u(nFramesLength)
+	clockTimestamp.Seconds, clockTimestamp.Minutes, clockTimestamp.Hours = -1, -1, -1
+	if fullTimestamp {
+		clockTimestamp.Seconds = reader.u(6)
+		clockTimestamp.Minutes = reader.u(6)
+		clockTimestamp.Hours = reader.u(5)
+		return
+	}
+	if reader.flag() {
+		clockTimestamp.Seconds = reader.u(6)
+		if reader.flag() {
+			clockTimestamp.Minutes = reader.u(6)
+			if reader.flag() {
+				clockTimestamp.Hours = reader.u(5)
+			}
+		}
+	}
+}
+
+func signExtend(value int, bits int) int {
+	if 0 != value&(1<<uint(bits-1)) {
+		return value - 1<<uint(bits)
+	}
+	return value
+}
@@ -0,0 +1,57 @@
+package rtspclient
+
+import (
+	"bytes"
+	"testing"
+)
+
+func TestH264SEI(t *testing.T) {
+	// 640x480 with VUI timing and pic_struct_present_flag
+	sps := []byte{0x67, 0x42, 0x00, 0x1e, 0xda, 0x02, 0x80, 0xf6, 0x84, 0x00, 0x00, 0x03, 0x00, 0x04,
+		0x00, 0x00, 0x03, 0x00, 0xca, 0x50}
+	uuid := [16]byte{0xdc, 0x45, 0xe9, 0xbd, 0xe6, 0xd9, 0x48, 0xb7, 0x96, 0x2c, 0xd8, 0x20, 0xd9, 0x23, 0xee, 0xef}
+	RegisterSEIUserDataDecoder(uuid, func(data []byte) (interface{}, error) {
+		return string(data), nil
+	})
+
+	sei := []byte{0x06, SEIPayloadPictureTiming, 6, 0x08, 0x04, 0x0c, 0x78, 0xf5, 0x40, SEIPayloadUserDataUnregistered, 19}
+	sei = append(sei, uuid[:]...)
+	sei = append(sei, 'a', 'b', 'c', 0x80)
+
+	rtpParser := newRtpParser(MediaSubsession{CodecName: "H264", RtpTimestampFrequency: 90000})
+	frames := parsingTestPackets(rtpParser, newTestRtpPacket(false, 1, 0, sps), newTestRtpPacket(false, 2, 0, sei))
+	if len(frames) != 2 || nil == frames[1].SEI {
+		t.Fatalf("sei not decoded: %v", frames)
+	}
+	info := frames[1].SEI
+	if len(info.Messages) != 2 {
+		t.Errorf("got %d messages, expected 2", len(info.Messages))
+	}
+
+	timing := info.PictureTiming
+	if nil == timing || timing.PicStruct != 0 || timing.CpbRemovalDelay != -1 || len(timing.ClockTimestamps) != 1 {
+		t.Fatalf("picture timing not decoded: %+v", timing)
+	}
+	clockTimestamp := timing.ClockTimestamps[0]
+	if clockTimestamp.NFrames != 12 || clockTimestamp.Seconds != 30 || clockTimestamp.Minutes != 15 || clockTimestamp.Hours != 10 {
+		t.Errorf("clock timestamp %+v", clockTimestamp)
+	}
+
+	if len(info.UserData) != 1 || info.UserData[0].UUID != uuid || !bytes.Equal(info.UserData[0].Data, []byte("abc")) ||
+		info.UserData[0].Decoded != "abc" {
+		t.Errorf("user data %+v", info.UserData)
+	}
+}
+
+func TestH265TimeCodeSEI(t *testing.T) {
+	// num_clock_ts 1, full timestamp 01:02:03 frame 4
+	sei := []byte{0x4e, 0x01, SEIPayloadTimeCode, 6, 0x60, 0x40, 0x20, 0x61, 0x04, 0x10, 0x80}
+	info, err := ParseH265SEI(sei, nil)
+	if nil != err || nil == info.PictureTiming || len(info.PictureTiming.ClockTimestamps) != 1 {
+		t.Fatalf("time code not decoded: %+v, %v", info, err)
+	}
+	clockTimestamp := info.PictureTiming.ClockTimestamps[0]
+	if clockTimestamp.NFrames != 4 || clockTimestamp.Seconds != 3 || clockTimestamp.Minutes != 2 || clockTimestamp.Hours != 1 {
+		t.Errorf("clock timestamp %+v", clockTimestamp)
+	}
+}
@@ -0,0 +1,152 @@
+package rtspclient
+
+import (
+	"errors"
+	"strconv"
+	"sync/atomic"
+	"time"
+
+	"github.com/NodeBoy2/rtspclient/tcpnetwork"
+)
+
+// SessionState is the lifecycle state of a session:
+//
+//	Init/Closed -> Connecting -> Described -> Ready -> Playing <-> Paused
+//	Connecting..Paused -> Closing -> Closed
+//
+// Play leaves Init or Closed, Close and a lost connection lead to Closed
+// through Closing. Every change is sent as RtspEventStateChanged.
+type SessionState int32
+
+const (
+	// SessionStateInit created, never played
+	SessionStateInit SessionState = iota
+	// SessionStateConnecting connecting to the server, or waiting for the sdp
+	SessionStateConnecting
+	// SessionStateDescribed the sdp is parsed, tracks are being set up
+	SessionStateDescribed
+	// SessionStateReady all tracks are set up
+	SessionStateReady
+	// SessionStatePlaying the server accepted PLAY
+	SessionStatePlaying
+	// SessionStatePaused the server accepted PAUSE
+	SessionStatePaused
+	// SessionStateClosing the connection is being closed
+	SessionStateClosing
+	// SessionStateClosed the connection is closed, Play may start over
+	SessionStateClosed
+)
+
+var sessionStateNames = [...]string{"Init", "Connecting", "Described", "Ready", "Playing", "Paused", "Closing", "Closed"}
+
+// sessionActiveStates are the states with a connection in use
+var sessionActiveStates = []SessionState{SessionStateConnecting, SessionStateDescribed, SessionStateReady, SessionStatePlaying, SessionStatePaused}
+
+func (state SessionState) String() string {
+	if 0 <= state && int(state) < len(sessionStateNames) {
+		return sessionStateNames[state]
+	}
+	return "SessionState(" + strconv.Itoa(int(state)) + ")"
+}
+
+// State returns the current state of the session, it is safe to call from
+// any goroutine.
+func (session *RtspClientSession) State() SessionState {
+	return SessionState(atomic.LoadInt32(&session.state))
+}
+
+// setState moves the session to state if it is in one of from, and returns
+// the state it was in. The change is sent from the calling goroutine.
+func (session *RtspClientSession) setState(state SessionState, from ...SessionState) (SessionState, bool) {
+	session.stateMutex.Lock()
+	previous := session.State()
+	changed := false
+	for _, allowed := range from {
+		if allowed == previous {
+			changed = true
+			break
+		}
+	}
+	if changed {
+		atomic.StoreInt32(&session.state, int32(state))
+		if SessionStateClosed == state {
+			session.tcpConn = nil
+		}
+	}
+	session.stateMutex.Unlock()
+
+	if changed {
+		session.logger.Debug("session state", "from", previous.String(), "to", state.String())
+		if nil != session.eventHandle {
+			event := newRtspEvent(RtspEventStateChanged, session, nil)
+			event.State = state
+			session.eventHandle(event)
+		}
+	}
+	return previous, changed
+}
+
+// connection returns the connection in use, nil when there is none.
+func (session *RtspClientSession) connection() *tcpnetwork.Connection {
+	session.stateMutex.Lock()
+	defer session.stateMutex.Unlock()
+	return session.tcpConn
+}
+
+// lockRequest takes the right to exchange requests with the server, waiting
+// at most the request timeout for the current exchange.
+func (session *RtspClientSession) lockRequest() bool {
+	select {
+	case session.requestLock <- struct{}{}:
+		return true
+	case <-time.After(time.Duration(session.timeoutSec) * time.Second):
+		return false
+	}
+}
+
+func (session *RtspClientSession) unlockRequest() {
+	<-session.requestLock
+}
+
+// exchange sends a request with send while the session is in from, and
+// moves it to state once the server accepted it. A timeout closes the
+// session.
+func (session *RtspClientSession) exchange(method string, from SessionState, state SessionState, send func() error) error {
+	if !session.lockRequest() {
+		return ErrRequestPending
+	}
+	err := ErrInvalidState
+	if from == session.State() {
+		err = send()
+		var response *RtspResponseContext
+		if nil == err {
+			response, err = session.waitResponse()
+		}
+		if nil == err {
+			err = checkResponse(method, response)
+		}
+		if nil == err {
+			if _, ok := session.setState(state, from); !ok {
+				err = ErrSessionClosed
+			}
+		}
+	}
+	session.unlockRequest()
+
+	if errors.Is(err, ErrTimeout) {
+		session.Close()
+	}
+	return err
+}
+
+// Pause pauses a playing session and waits for the reply.
+func (session *RtspClientSession) Pause() error {
+	return session.exchange("PAUSE", SessionStatePlaying, SessionStatePaused, session.SendPause)
+}
+
+// Resume plays a paused session again and waits for the reply.
+func (session *RtspClientSession) Resume() error {
+	return session.exchange("PLAY", SessionStatePaused, SessionStatePlaying, func() error {
+		return session.SendPlay(0, 1)
+	})
+}
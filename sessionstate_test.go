@@ -0,0 +1,241 @@
+package rtspclient
+
+import (
+	"bufio"
+	"errors"
+	"net"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+const testSessionSdp = "v=0\r\no=- 0 0 IN IP4 127.0.0.1\r\ns=test\r\nt=0 0\r\n" +
+	"m=audio 0 RTP/AVP 8\r\na=rtpmap:8 PCMA/8000\r\na=control:trackID=1\r\n"
+
+// testRtspInterleaved is an rtp packet of the PCMA track and a sender report,
+// sent after the reply to PLAY so that the session parses them while it is
+// used from other goroutines.
+var testRtspInterleaved = []byte{
+	'$', 0x00, 0x00, 0x0e,
+	0x80, 0x08, 0x00, 0x01, 0x00, 0x00, 0x00, 0xa0, 0x11, 0x22, 0x33, 0x44, 0xd5, 0xd5,
+	'$', 0x01, 0x00, 0x1c,
+	0x80, RtcpTypeSR, 0x00, 0x06, 0x11, 0x22, 0x33, 0x44,
+	0xe2, 0xd2, 0x0e, 0x00, 0x80, 0x00, 0x00, 0x00,
+	0x00, 0x00, 0x00, 0xa0, 0x00, 0x00, 0x00, 0x01, 0x00, 0x00, 0x00, 0x02,
+}
+
+// testRtspServer replies 200 to the requests of its connections, except to
+// the silent method.
+type testRtspServer struct {
+	listener net.Listener
+	silent   string
+}
+
+func newTestRtspServer(t *testing.T, silent string) *testRtspServer {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if nil != err {
+		t.Fatal(err)
+	}
+	server := &testRtspServer{listener: listener, silent: silent}
+	go func() {
+		for {
+			conn, err := listener.Accept()
+			if nil != err {
+				return
+			}
+			go server.serve(conn)
+		}
+	}()
+	return server
+}
+
+func (server *testRtspServer) url() string {
+	return "rtsp://" + server.listener.Addr().String() + "/live"
+}
+
+func (server *testRtspServer) serve(conn net.Conn) {
+	defer conn.Close()
+	reader := bufio.NewReader(conn)
+	for {
+		var method, cseq string
+		for {
+			line, err := reader.ReadString('\n')
+			if nil != err {
+				return
+			}
+			line = strings.TrimSpace(line)
+			if "" == line {
+				break
+			}
+			if "" == method {
+				method = strings.Fields(line)[0]
+			} else if strings.HasPrefix(line, "CSeq:") {
+				cseq = strings.TrimSpace(line[len("CSeq:"):])
+			}
+		}
+		if method == server.silent {
+			continue
+		}
+		response := "RTSP/1.0 200 OK\r\nCSeq: " + cseq + "\r\n"
+		switch method {
+		case "DESCRIBE":
+			response += "Content-Type: application/sdp\r\nContent-Length: " + strconv.Itoa(len(testSessionSdp)) + "\r\n\r\n" + testSessionSdp
+		case "SETUP":
+			response += "Session: 12345678\r\nTransport: RTP/AVP/TCP;unicast;interleaved=0-1\r\n\r\n"
+		default:
+			response += "\r\n"
+		}
+		if _, err := conn.Write([]byte(response)); nil != err {
+			return
+		}
+		if "PLAY" == method {
+			if _, err := conn.Write(testRtspInterleaved); nil != err {
+				return
+			}
+		}
+	}
+}
+
+func waitSessionState(t *testing.T, session *RtspClientSession, state SessionState) {
+	deadline := time.Now().Add(5 * time.Second)
+	for state != session.State() {
+		if time.Now().After(deadline) {
+			t.Fatalf("state %v, expected %v", session.State(), state)
+		}
+		time.Sleep(time.Millisecond)
+	}
+}
+
+func TestSessionStateMachine(t *testing.T) {
+	server := newTestRtspServer(t, "")
+	defer server.listener.Close()
+	var mutex sync.Mutex
+	var states []SessionState
+	session := NewRtspClientSession(func(*RtspData) {}, func(event *RtspEvent) {
+		if RtspEventStateChanged == event.EventType {
+			mutex.Lock()
+			states = append(states, event.State)
+			mutex.Unlock()
+		}
+	})
+	session.SetLogger(nil)
+	if SessionStateInit != session.State() || "Init" != session.State().String() {
+		t.Fatalf("state %v", session.State())
+	}
+	if err := session.Pause(); !errors.Is(err, ErrInvalidState) {
+		t.Errorf("pause before play %v", err)
+	}
+
+	if err := session.Play(server.url()); nil != err {
+		t.Fatal(err)
+	}
+	if err := session.Play(server.url()); !errors.Is(err, ErrSessionConnected) {
+		t.Errorf("second play %v", err)
+	}
+	if err := session.Pause(); nil != err {
+		t.Fatal(err)
+	}
+	if err := session.Pause(); !errors.Is(err, ErrInvalidState) {
+		t.Errorf("second pause %v", err)
+	}
+	if err := session.Resume(); nil != err {
+		t.Fatal(err)
+	}
+	session.Close()
+	waitSessionState(t, session, SessionStateClosed)
+	session.Close()
+
+	expected := []SessionState{SessionStateConnecting, SessionStateDescribed, SessionStateReady, SessionStatePlaying,
+		SessionStatePaused, SessionStatePlaying, SessionStateClosing, SessionStateClosed}
+	mutex.Lock()
+	if len(expected) != len(states) {
+		t.Fatalf("states %v", states)
+	}
+	for i := range expected {
+		if expected[i] != states[i] {
+			t.Fatalf("states %v", states)
+		}
+	}
+	mutex.Unlock()
+
+	// a closed session plays again
+	if err := session.Play(server.url()); nil != err {
+		t.Fatal(err)
+	}
+	if SessionStatePlaying != session.State() || 1 != session.Stats().Reconnects {
+		t.Errorf("state %v stats %+v", session.State(), session.Stats())
+	}
+	session.Close()
+	waitSessionState(t, session, SessionStateClosed)
+}
+
+func TestSessionCloseWhileConnecting(t *testing.T) {
+	server := newTestRtspServer(t, "DESCRIBE")
+	defer server.listener.Close()
+	session := NewRtspClientSession(func(*RtspData) {}, nil)
+	session.SetLogger(nil)
+	result := make(chan error)
+	go func() {
+		result <- session.Play(server.url())
+	}()
+	for nil == session.connection() {
+		time.Sleep(time.Millisecond)
+	}
+	session.Close()
+	select {
+	case err := <-result:
+		if !errors.Is(err, ErrDisconnected) {
+			t.Errorf("play %v", err)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("play not aborted")
+	}
+	if SessionStateClosed != session.State() {
+		t.Errorf("state %v", session.State())
+	}
+}
+
+func TestSessionConcurrentPlayPauseClose(t *testing.T) {
+	server := newTestRtspServer(t, "")
+	defer server.listener.Close()
+	for i := 0; i < 5; i++ {
+		session := NewRtspClientSession(func(*RtspData) {}, func(*RtspEvent) {})
+		session.SetLogger(nil)
+		var wg sync.WaitGroup
+		for j := 0; j < 3; j++ {
+			wg.Add(4)
+			go func() {
+				defer wg.Done()
+				session.Play(server.url())
+			}()
+			go func() {
+				defer wg.Done()
+				session.Pause()
+				session.Resume()
+			}()
+			go func(j int) {
+				defer wg.Done()
+				time.Sleep(time.Duration(j) * 10 * time.Millisecond)
+				session.Close()
+			}(j)
+			go func() {
+				defer wg.Done()
+				for k := 0; k < 50; k++ {
+					session.State()
+					session.Stats()
+					session.GetSenderReport(0)
+					session.RequestKeyframe(0)
+					session.GetRtpParseHandler(0)
+					for range session.GetMediaMap() {
+					}
+					time.Sleep(time.Millisecond)
+				}
+			}()
+		}
+		wg.Wait()
+		session.Close()
+		waitSessionState(t, session, SessionStateClosed)
+	}
+}
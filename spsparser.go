@@ -0,0 +1,516 @@
+package rtspclient
+
+import (
+	"errors"
+)
+
+// SPSInfo describes the video of an H.264 or H.265 sequence parameter set.
+type SPSInfo struct {
+	ProfileIDC      int
+	LevelIDC        int // level times 10 (H.264) or 30 (H.265)
+	Tier            int // H.265 general_tier_flag
+	ChromaFormatIDC int // 0 monochrome, 1 4:2:0, 2 4:2:2, 3 4:4:4
+	BitDepthLuma    int
+	BitDepthChroma  int
+	Width           int // after cropping
+	Height          int
+	FrameRate       float64 // from the VUI timing info, 0 if absent
+
+	picTiming seiTimingParams // VUI fields the picture timing SEI depends on
+}
+
+// seiTimingParams are the VUI and HRD fields needed to parse a picture
+// timing SEI message.
+type seiTimingParams struct {
+	cpbDpbDelaysPresent   bool
+	cpbRemovalDelayLength int
+	dpbOutputDelayLength  int
+	timeOffsetLength      int
+	picStructPresent      bool // H.264 pic_struct_present_flag, H.265 frame_field_info_present_flag
+}
+
+var errSPSInvalid = errors.New("sps: invalid parameter set")
+
+// spsReader reads the syntax elements of a parameter set and keeps the first
+// error, so a parser can check it once.
+type spsReader struct {
+	reader *bitReader
+	err    error
+}
+
+func newSPSReader(rbsp []byte) *spsReader {
+	return &spsReader{reader: newBitReader(rbsp)}
+}
+
+func (reader *spsReader) u(n int) int {
+	if nil != reader.err {
+		return 0
+	}
+	value, err := reader.reader.readBits(n)
+	reader.err = err
+	return int(value)
+}
+
+func (reader *spsReader) flag() bool {
+	return 1 == reader.u(1)
+}
+
+func (reader *spsReader) ue() int {
+	if nil != reader.err {
+		return 0
+	}
+	value, err := reader.reader.readUE()
+	reader.err = err
+	return int(value)
+}
+
+func (reader *spsReader) se() int {
+	if nil != reader.err {
+		return 0
+	}
+	value, err := reader.reader.readSE()
+	reader.err = err
+	return int(value)
+}
+
+func (reader *spsReader) skip(n int) {
+	if nil != reader.err {
+		return
+	}
+	reader.err = reader.reader.skipBits(n)
+}
+
+// ParseH264SPS decodes an H.264 sequence parameter set NAL unit.
+func ParseH264SPS(nalu []byte) (*SPSInfo, error) {
+	if len(nalu) < 4 || h264NalSPS != nalu[0]&0x1f {
+		return nil, errSPSInvalid
+	}
+	reader := newSPSReader(unescapeRBSP(nalu[1:]))
+	info := &SPSInfo{ChromaFormatIDC: 1, BitDepthLuma: 8, BitDepthChroma: 8}
+
+	info.ProfileIDC = reader.u(8)
+	reader.skip(8) // constraint flags
+	info.LevelIDC = reader.u(8)
+	reader.ue() // seq_parameter_set_id
+
+	separateColourPlane := false
+	switch info.ProfileIDC {
+	case 100, 110, 122, 244, 44, 83, 86, 118, 128, 138, 139, 134, 135:
+		info.ChromaFormatIDC = reader.ue()
+		if 3 == info.ChromaFormatIDC {
+			separateColourPlane = reader.flag()
+		}
+		info.BitDepthLuma = 8 + reader.ue()
+		info.BitDepthChroma = 8 + reader.ue()
+		reader.skip(1) // qpprime_y_zero_transform_bypass_flag
+		if reader.flag() {
+			// seq_scaling_matrix_present_flag
+			listCount := 8
+			if 3 == info.ChromaFormatIDC {
+				listCount = 12
+			}
+			for index := 0; index < listCount; index++ {
+				if !reader.flag() {
+					continue
+				}
+				size := 16
+				if 6 <= index {
+					size = 64
+				}
+				skipH264ScalingList(reader, size)
+			}
+		}
+	}
+
+	reader.ue() // log2_max_frame_num_minus4
+	switch reader.ue() {
+	case 0:
+		reader.ue() // log2_max_pic_order_cnt_lsb_minus4
+	case 1:
+		reader.skip(1) // delta_pic_order_always_zero_flag
+		reader.se()    // offset_for_non_ref_pic
+		reader.se()    // offset_for_top_to_bottom_field
+		cycle := reader.ue()
+		for index := 0; index < cycle && nil == reader.err; index++ {
+			reader.se()
+		}
+	}
+	reader.ue()    // max_num_ref_frames
+	reader.skip(1) // gaps_in_frame_num_value_allowed_flag
+	widthInMbs := reader.ue() + 1
+	heightInMapUnits := reader.ue() + 1
+	frameMbsOnly := reader.flag()
+	if !frameMbsOnly {
+		reader.skip(1) // mb_adaptive_frame_field_flag
+	}
+	reader.skip(1) // direct_8x8_inference_flag
+
+	var cropLeft, cropRight, cropTop, cropBottom int
+	if reader.flag() {
+		cropLeft, cropRight, cropTop, cropBottom = reader.ue(), reader.ue(), reader.ue(), reader.ue()
+	}
+	frameHeightFactor := 2
+	if frameMbsOnly {
+		frameHeightFactor = 1
+	}
+	cropUnitX, cropUnitY := 1, frameHeightFactor
+	if !separateColourPlane && 0 != info.ChromaFormatIDC {
+		subWidth, subHeight := chromaSubsampling(info.ChromaFormatIDC)
+		cropUnitX, cropUnitY = subWidth, subHeight*frameHeightFactor
+	}
+	info.Width = widthInMbs*16 - cropUnitX*(cropLeft+cropRight)
+	info.Height = frameHeightFactor*heightInMapUnits*16 - cropUnitY*(cropTop+cropBottom)
+
+	if nil != reader.err {
+		return nil, reader.err
+	}
+	if reader.flag() {
+		// vui_parameters_present_flag
+		parsingH264VUI(reader, info)
+	}
+	// a truncated VUI still leaves a usable picture size
+	return info, nil
+}
+
+func skipH264ScalingList(reader *spsReader, size int) {
+	lastScale, nextScale := 8, 8
+	for index := 0; index < size && nil == reader.err; index++ {
+		if 0 != nextScale {
+			nextScale = (lastScale + reader.se() + 256) % 256
+		}
+		if 0 != nextScale {
+			lastScale = nextScale
+		}
+	}
+}
+
+func parsingH264VUI(reader *spsReader, info *SPSInfo) {
+	if reader.flag() {
+		// aspect_ratio_info_present_flag
+		if 255 == reader.u(8) {
+			reader.skip(32) // sar_width, sar_height
+		}
+	}
+	if reader.flag() {
+		reader.skip(1) // overscan_appropriate_flag
+	}
+	if reader.flag() {
+		// video_signal_type_present_flag
+		reader.skip(4)
+		if reader.flag() {
+			reader.skip(24) // colour description
+		}
+	}
+	if reader.flag() {
+		reader.ue() // chroma_sample_loc_type_top_field
+		reader.ue() // chroma_sample_loc_type_bottom_field
+	}
+	if reader.flag() {
+		// timing_info_present_flag
+		numUnitsInTick := reader.u(32)
+		timeScale := reader.u(32)
+		if nil == reader.err && 0 != numUnitsInTick {
+			info.FrameRate = float64(timeScale) / float64(2*numUnitsInTick)
+		}
+		reader.skip(1) // fixed_frame_rate_flag
+	}
+	nalHrd := reader.flag()
+	if nalHrd {
+		parsingH264HRD(reader, &info.picTiming)
+	}
+	vclHrd := reader.flag()
+	if vclHrd {
+		parsingH264HRD(reader, &info.picTiming)
+	}
+	if nalHrd || vclHrd {
+		info.picTiming.cpbDpbDelaysPresent = true
+		reader.skip(1) // low_delay_hrd_flag
+	}
+	picStructPresent := reader.flag()
+	if nil != reader.err {
+		info.picTiming = seiTimingParams{}
+		return
+	}
+	info.picTiming.picStructPresent = picStructPresent
+}
+
+func parsingH264HRD(reader *spsReader, params *seiTimingParams) {
+	cpbCount := reader.ue() + 1
+	if 32 < cpbCount {
+		reader.err = errSPSInvalid
+		return
+	}
+	reader.skip(8) // bit_rate_scale, cpb_size_scale
+	for index := 0; index < cpbCount && nil == reader.err; index++ {
+		reader.ue()    // bit_rate_value_minus1
+		reader.ue()    // cpb_size_value_minus1
+		reader.skip(1) // cbr_flag
+	}
+	reader.skip(5) // initial_cpb_removal_delay_length_minus1
+	params.cpbRemovalDelayLength = reader.u(5) + 1
+	params.dpbOutputDelayLength = reader.u(5) + 1
+	params.timeOffsetLength = reader.u(5)
+}
+
+// chromaSubsampling returns SubWidthC and SubHeightC.
+func chromaSubsampling(chromaFormatIDC int) (int, int) {
+	switch chromaFormatIDC {
+	case 1:
+		return 2, 2
+	case 2:
+		return 2, 1
+	}
+	return 1, 1
+}
+
+// ParseH265SPS decodes an H.265 sequence parameter set NAL unit.
+func ParseH265SPS(nalu []byte) (*SPSInfo, error) {
+	if len(nalu) < 4 || hevcNalSPS != int(nalu[0]>>1)&0x3f {
+		return nil, errSPSInvalid
+	}
+	reader := newSPSReader(unescapeRBSP(nalu[2:]))
+	info := &SPSInfo{}
+
+	reader.skip(4) // sps_video_parameter_set_id
+	maxSubLayersMinus1 := reader.u(3)
+	reader.skip(1) // sps_temporal_id_nesting_flag
+	parsingH265ProfileTierLevel(reader, info, maxSubLayersMinus1)
+	reader.ue() // sps_seq_parameter_set_id
+	info.ChromaFormatIDC = reader.ue()
+	separateColourPlane := false
+	if 3 == info.ChromaFormatIDC {
+		separateColourPlane = reader.flag()
+	}
+	info.Width = reader.ue()
+	info.Height = reader.ue()
+	if reader.flag() {
+		// conformance_window_flag
+		subWidth, subHeight := 1, 1
+		if !separateColourPlane {
+			subWidth, subHeight = chromaSubsampling(info.ChromaFormatIDC)
+		}
+		left, right, top, bottom := reader.ue(), reader.ue(), reader.ue(), reader.ue()
+		info.Width -= subWidth * (left + right)
+		info.Height -= subHeight * (top + bottom)
+	}
+	info.BitDepthLuma = 8 + reader.ue()
+	info.BitDepthChroma = 8 + reader.ue()
+	if nil != reader.err {
+		return nil, reader.err
+	}
+
+	log2MaxPocLsb := reader.ue() + 4
+	subLayerOrderingInfo := reader.flag()
+	start := maxSubLayersMinus1
+	if subLayerOrderingInfo {
+		start = 0
+	}
+	for index := start; index <= maxSubLayersMinus1; index++ {
+		reader.ue() // sps_max_dec_pic_buffering_minus1
+		reader.ue() // sps_max_num_reorder_pics
+		reader.ue() // sps_max_latency_increase_plus1
+	}
+	for index := 0; index < 6; index++ {
+		// coding and transform block sizes, transform hierarchy depths
+		reader.ue()
+	}
+	if reader.flag() && reader.flag() {
+		// scaling_list_enabled_flag, sps_scaling_list_data_present_flag
+		skipH265ScalingListData(reader)
+	}
+	reader.skip(2) // amp_enabled_flag, sample_adaptive_offset_enabled_flag
+	if reader.flag() {
+		// pcm_enabled_flag
+		reader.skip(8)
+		reader.ue()
+		reader.ue()
+		reader.skip(1)
+	}
+	numShortTermRefPicSets := reader.ue()
+	if 64 < numShortTermRefPicSets {
+		return info, nil
+	}
+	numDeltaPocs := make([]int, numShortTermRefPicSets)
+	for index := 0; index < numShortTermRefPicSets && nil == reader.err; index++ {
+		numDeltaPocs[index] = skipH265ShortTermRefPicSet(reader, index, numDeltaPocs)
+	}
+	if reader.flag() {
+		// long_term_ref_pics_present_flag
+		count := reader.ue()
+		for index := 0; index < count && nil == reader.err; index++ {
+			reader.skip(log2MaxPocLsb + 1)
+		}
+	}
+	reader.skip(2) // sps_temporal_mvp_enabled_flag, strong_intra_smoothing_enabled_flag
+	if nil == reader.err && reader.flag() {
+		// vui_parameters_present_flag
+		parsingH265VUI(reader, info)
+	}
+	return info, nil
+}
+
+func parsingH265ProfileTierLevel(reader *spsReader, info *SPSInfo, maxSubLayersMinus1 int) {
+	reader.skip(2) // general_profile_space
+	info.Tier = reader.u(1)
+	info.ProfileIDC = reader.u(5)
+	reader.skip(32 + 48) // compatibility and constraint flags
+	info.LevelIDC = reader.u(8)
+
+	profilePresent := make([]bool, maxSubLayersMinus1)
+	levelPresent := make([]bool, maxSubLayersMinus1)
+	for index := 0; index < maxSubLayersMinus1; index++ {
+		profilePresent[index] = reader.flag()
+		levelPresent[index] = reader.flag()
+	}
+	if 0 < maxSubLayersMinus1 {
+		reader.skip(2 * (8 - maxSubLayersMinus1))
+	}
+	for index := 0; index < maxSubLayersMinus1; index++ {
+		if profilePresent[index] {
+			reader.skip(88)
+		}
+		if levelPresent[index] {
+			reader.skip(8)
+		}
+	}
+}
+
+func skipH265ScalingListData(reader *spsReader) {
+	for sizeID := 0; sizeID < 4; sizeID++ {
+		step := 1
+		if 3 == sizeID {
+			step = 3
+		}
+		for matrixID := 0; matrixID < 6; matrixID += step {
+			if !reader.flag() {
+				reader.ue() // scaling_list_pred_matrix_id_delta
+				continue
+			}
+			coefNum := 1 << uint(4+(sizeID<<1))
+			if 64 < coefNum {
+				coefNum = 64
+			}
+			if 1 < sizeID {
+				reader.se() // scaling_list_dc_coef_minus8
+			}
+			for index := 0; index < coefNum && nil == reader.err; index++ {
+				reader.se()
+			}
+		}
+	}
+}
+
+// skipH265ShortTermRefPicSet reads st_ref_pic_set(index) and returns its
+// NumDeltaPocs.
+func skipH265ShortTermRefPicSet(reader *spsReader, index int, numDeltaPocs []int) int {
+	if 0 != index && reader.flag() {
+		// inter_ref_pic_set_prediction_flag
+		reader.skip(1) // delta_rps_sign
+		reader.ue()    // abs_delta_rps_minus1
+		count := 0
+		for j := 0; j <= numDeltaPocs[index-1] && nil == reader.err; j++ {
+			used := reader.flag()
+			if used || reader.flag() {
+				count++
+			}
+		}
+		return count
+	}
+	numNegative := reader.ue()
+	numPositive := reader.ue()
+	if 32 < numNegative+numPositive {
+		reader.err = errSPSInvalid
+		return 0
+	}
+	for j := 0; j < numNegative+numPositive; j++ {
+		reader.ue()    // delta_poc_minus1
+		reader.skip(1) // used_by_curr_pic_flag
+	}
+	return numNegative + numPositive
+}
+
+func parsingH265VUI(reader *spsReader, info *SPSInfo) {
+	if reader.flag() {
+		// aspect_ratio_info_present_flag
+		if 255 == reader.u(8) {
+			reader.skip(32)
+		}
+	}
+	if reader.flag() {
+		reader.skip(1) // overscan_appropriate_flag
+	}
+	if reader.flag() {
+		// video_signal_type_present_flag
+		reader.skip(4)
+		if reader.flag() {
+			reader.skip(24)
+		}
+	}
+	if reader.flag() {
+		reader.ue()
+		reader.ue()
+	}
+	reader.skip(2) // neutral_chroma_indication_flag, field_seq_flag
+	frameFieldInfoPresent := reader.flag()
+	if nil == reader.err {
+		info.picTiming.picStructPresent = frameFieldInfoPresent
+	}
+	if reader.flag() {
+		// default_display_window_flag
+		reader.ue()
+		reader.ue()
+		reader.ue()
+		reader.ue()
+	}
+	if reader.flag() {
+		// vui_timing_info_present_flag
+		numUnitsInTick := reader.u(32)
+		timeScale := reader.u(32)
+		if nil == reader.err && 0 != numUnitsInTick {
+			info.FrameRate = float64(timeScale) / float64(numUnitsInTick)
+		}
+	}
+}
+
+// ParseH265VPS decodes the profile, tier, level and timing info of an H.265
+// video parameter set NAL unit.
+func ParseH265VPS(nalu []byte) (*SPSInfo, error) {
+	if len(nalu) < 4 || hevcNalVPS != int(nalu[0]>>1)&0x3f {
+		return nil, errSPSInvalid
+	}
+	reader := newSPSReader(unescapeRBSP(nalu[2:]))
+	info := &SPSInfo{}
+
+	reader.skip(12) // vps_video_parameter_set_id, flags, vps_max_layers_minus1
+	maxSubLayersMinus1 := reader.u(3)
+	reader.skip(17) // vps_temporal_id_nesting_flag, vps_reserved_0xffff_16bits
+	parsingH265ProfileTierLevel(reader, info, maxSubLayersMinus1)
+	if nil != reader.err {
+		return nil, reader.err
+	}
+	subLayerOrderingInfo := reader.flag()
+	start := maxSubLayersMinus1
+	if subLayerOrderingInfo {
+		start = 0
+	}
+	for index := start; index <= maxSubLayersMinus1; index++ {
+		reader.ue()
+		reader.ue()
+		reader.ue()
+	}
+	maxLayerID := reader.u(6)
+	numLayerSets := reader.ue() + 1
+	if 1024 < numLayerSets {
+		return info, nil
+	}
+	reader.skip((numLayerSets - 1) * (maxLayerID + 1))
+	if reader.flag() {
+		// vps_timing_info_present_flag
+		numUnitsInTick := reader.u(32)
+		timeScale := reader.u(32)
+		if nil == reader.err && 0 != numUnitsInTick {
+			info.FrameRate = float64(timeScale) / float64(numUnitsInTick)
+		}
+	}
+	return info, nil
+}
@@ -0,0 +1,53 @@
+package rtspclient
+
+import (
+	"encoding/base64"
+	"testing"
+)
+
+func TestParseH264SPS(t *testing.T) {
+	sps, _ := base64.StdEncoding.DecodeString("Z0IAKeKQFAe2AtwEBAaQeJEV")
+	info, err := ParseH264SPS(sps)
+	if nil != err || info.ProfileIDC != 66 || info.LevelIDC != 41 || info.Width != 640 || info.Height != 480 {
+		t.Errorf("got %+v, %v", info, err)
+	}
+
+	// 1920x1088 cropped to 1080, 25 fps, with emulation prevention bytes
+	sps = []byte{0x67, 0x42, 0x00, 0x1e, 0xda, 0x01, 0xe0, 0x08, 0x9f, 0x96, 0x10, 0x00, 0x00, 0x03, 0x00,
+		0x10, 0x00, 0x00, 0x03, 0x03, 0x2a}
+	info, err = ParseH264SPS(sps)
+	if nil != err || info.Width != 1920 || info.Height != 1080 || info.FrameRate != 25 || info.ChromaFormatIDC != 1 {
+		t.Errorf("got %+v, %v", info, err)
+	}
+}
+
+func TestParseH265SPS(t *testing.T) {
+	sps, _ := base64.StdEncoding.DecodeString("QgEBAWAAAAMAkAAAAwAAAwBdoAKAgC0WWVmkkyvAQAAAAwBAAAAHgg==")
+	info, err := ParseH265SPS(sps)
+	if nil != err || info.ProfileIDC != 1 || info.LevelIDC != 93 || info.Width != 1280 || info.Height != 720 || info.BitDepthLuma != 8 {
+		t.Errorf("got %+v, %v", info, err)
+	}
+}
+
+func TestVideoInfoUpdate(t *testing.T) {
+	media := MediaSubsession{
+		CodecName:             "H264",
+		RtpTimestampFrequency: 90000,
+		Fmtp:                  getFmtParame("96 sprop-parameter-sets=Z0IAKeKQFAe2AtwEBAaQeJEV,aM48gA=="),
+	}
+	rtpParser := newRtpParser(media)
+	updater := rtpParser.rtpSourceHandler.(videoInfoUpdater)
+	if !updater.updateVideoInfo(&media) || media.VideoWidth != 640 || media.VideoHeight != 480 {
+		t.Fatalf("sprop sps not applied: %+v", media)
+	}
+	if updater.updateVideoInfo(&media) {
+		t.Error("unchanged sps reported")
+	}
+
+	sps := []byte{0x67, 0x42, 0x00, 0x1e, 0xda, 0x01, 0xe0, 0x08, 0x9f, 0x96, 0x10, 0x00, 0x00, 0x03, 0x00,
+		0x10, 0x00, 0x00, 0x03, 0x03, 0x2a}
+	parsingTestPackets(rtpParser, newTestRtpPacket(true, 1, 0, sps))
+	if !updater.updateVideoInfo(&media) || media.VideoWidth != 1920 || media.VideoFramerate != 25 || nil == media.VideoSPS {
+		t.Errorf("in-band sps not applied: %+v", media)
+	}
+}
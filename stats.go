@@ -0,0 +1,183 @@
+package rtspclient
+
+import (
+	"math"
+	"strings"
+	"sync/atomic"
+	"time"
+)
+
+// TrackStats is a snapshot of the reception of a track.
+type TrackStats struct {
+	ChannelNum        int
+	CodecName         string
+	BytesReceived     uint64
+	PacketsReceived   uint64
+	PacketsLost       int64 // expected minus received, negative with duplicates
+	PacketsReordered  uint64
+	PacketsDuplicated uint64
+	FramesReceived    uint64
+	Bitrate           float64       // bits per second over the last second, decaying while stalled
+	FrameRate         float64       // frames per second over the last second, decaying while stalled
+	KeyframeInterval  time.Duration // between the last two key frames
+	Jitter            time.Duration // interarrival jitter (RFC 3550)
+	RTT               time.Duration // round trip to the sender from RTCP XR, 0 if unknown
+	LastFrameAge      time.Duration // since the last frame, 0 before the first one
+}
+
+// SessionStats is a snapshot of a session.
+type SessionStats struct {
+	URL            string // without credentials
+	Reconnects     uint64
+	RequestLatency map[string]time.Duration // of the last request of every method
+	Tracks         []TrackStats
+}
+
+var rtspMethods = []string{"OPTIONS", "DESCRIBE", "SETUP", "PLAY", "PAUSE", "TEARDOWN", "GET_PARAMETER", "SET_PARAMETER"}
+
+// sessionCounters are written by the session and read by Stats with atomics.
+// The 64 bit fields come first to stay aligned on 32 bit platforms.
+type sessionCounters struct {
+	connects       uint64
+	requestLatency [8]int64 // per rtspMethods, in ns
+	url            atomic.Value
+	tracks         atomic.Value // []*RtpParser in channel order
+}
+
+// trackCounters are written by the connection routine and read by Stats with
+// atomics. The 64 bit fields come first to stay aligned on 32 bit platforms.
+type trackCounters struct {
+	bytes            uint64
+	packets          uint64
+	lost             int64
+	reordered        uint64
+	duplicated       uint64
+	frames           uint64
+	bitrate          uint64 // float64 bits
+	frameRate        uint64 // float64 bits
+	keyframeInterval int64
+	jitter           int64
+	rtt              int64
+	lastFrame        int64 // unix ns
+	windowStart      int64 // unix ns
+	windowBytes      uint64
+	windowFrames     uint64
+	channelNum       int
+	codecName        string
+	lastKeyframe     time.Time
+}
+
+func (counters *trackCounters) onPacket(size int, arrival time.Time) {
+	atomic.AddUint64(&counters.bytes, uint64(size))
+	atomic.AddUint64(&counters.packets, 1)
+	windowStart := atomic.LoadInt64(&counters.windowStart)
+	if 0 == windowStart {
+		windowStart = arrival.UnixNano()
+		atomic.StoreInt64(&counters.windowStart, windowStart)
+	}
+	if elapsed := time.Duration(arrival.UnixNano() - windowStart); time.Second <= elapsed {
+		atomic.StoreUint64(&counters.bitrate, math.Float64bits(float64(atomic.LoadUint64(&counters.windowBytes))*8/elapsed.Seconds()))
+		atomic.StoreUint64(&counters.frameRate, math.Float64bits(float64(atomic.LoadUint64(&counters.windowFrames))/elapsed.Seconds()))
+		atomic.StoreInt64(&counters.windowStart, arrival.UnixNano())
+		atomic.StoreUint64(&counters.windowBytes, 0)
+		atomic.StoreUint64(&counters.windowFrames, 0)
+	}
+	atomic.AddUint64(&counters.windowBytes, uint64(size))
+}
+
+func (counters *trackCounters) onFrame(isKeyFrame bool, arrival time.Time) {
+	atomic.AddUint64(&counters.frames, 1)
+	atomic.StoreInt64(&counters.lastFrame, arrival.UnixNano())
+	atomic.AddUint64(&counters.windowFrames, 1)
+	if isKeyFrame {
+		if !counters.lastKeyframe.IsZero() {
+			atomic.StoreInt64(&counters.keyframeInterval, int64(arrival.Sub(counters.lastKeyframe)))
+		}
+		counters.lastKeyframe = arrival
+	}
+}
+
+func (counters *trackCounters) onReception(stats *receptionStats) {
+	atomic.StoreInt64(&counters.lost, stats.cumulativeLost())
+	atomic.StoreUint64(&counters.reordered, stats.reordered)
+	atomic.StoreUint64(&counters.duplicated, stats.duplicated)
+	if 0 < stats.clockRate {
+		atomic.StoreInt64(&counters.jitter, int64(stats.jitter*float64(time.Second)/float64(stats.clockRate)))
+	}
+}
+
+func (counters *trackCounters) snapshot(now time.Time) TrackStats {
+	trackStats := TrackStats{
+		ChannelNum:        counters.channelNum,
+		CodecName:         counters.codecName,
+		BytesReceived:     atomic.LoadUint64(&counters.bytes),
+		PacketsReceived:   atomic.LoadUint64(&counters.packets),
+		PacketsLost:       atomic.LoadInt64(&counters.lost),
+		PacketsReordered:  atomic.LoadUint64(&counters.reordered),
+		PacketsDuplicated: atomic.LoadUint64(&counters.duplicated),
+		FramesReceived:    atomic.LoadUint64(&counters.frames),
+		Bitrate:           math.Float64frombits(atomic.LoadUint64(&counters.bitrate)),
+		FrameRate:         math.Float64frombits(atomic.LoadUint64(&counters.frameRate)),
+		KeyframeInterval:  time.Duration(atomic.LoadInt64(&counters.keyframeInterval)),
+		Jitter:            time.Duration(atomic.LoadInt64(&counters.jitter)),
+		RTT:               time.Duration(atomic.LoadInt64(&counters.rtt)),
+	}
+	if lastFrame := atomic.LoadInt64(&counters.lastFrame); 0 != lastFrame {
+		trackStats.LastFrameAge = now.Sub(time.Unix(0, lastFrame))
+	}
+	if windowStart := atomic.LoadInt64(&counters.windowStart); 0 != windowStart {
+		if elapsed := now.Sub(time.Unix(0, windowStart)); time.Second < elapsed {
+			// no packet closed the window, the rates decay over it
+			trackStats.Bitrate = float64(atomic.LoadUint64(&counters.windowBytes)) * 8 / elapsed.Seconds()
+			trackStats.FrameRate = float64(atomic.LoadUint64(&counters.windowFrames)) / elapsed.Seconds()
+		}
+	}
+	return trackStats
+}
+
+// recordRequestLatency keeps the latency of the last request of a method.
+func (session *RtspClientSession) recordRequestLatency(request []byte, latency time.Duration) {
+	method := string(request)
+	if index := strings.IndexByte(method, ' '); -1 != index {
+		method = method[:index]
+	}
+	for index, name := range rtspMethods {
+		if name == method {
+			atomic.StoreInt64(&session.counters.requestLatency[index], int64(latency))
+			return
+		}
+	}
+}
+
+// publishTracks makes the tracks set up so far visible to Stats.
+func (session *RtspClientSession) publishTracks() {
+	channelMap := session.channels()
+	tracks := make([]*RtpParser, 0, len(channelMap))
+	for channelNum := 0; len(tracks) < len(channelMap); channelNum += 2 {
+		if rtpParser, ok := channelMap[channelNum]; ok {
+			tracks = append(tracks, rtpParser)
+		}
+	}
+	session.counters.tracks.Store(tracks)
+}
+
+// Stats returns a snapshot of the session and its tracks. It is safe to call
+// from any goroutine.
+func (session *RtspClientSession) Stats() SessionStats {
+	now := time.Now()
+	stats := SessionStats{RequestLatency: make(map[string]time.Duration)}
+	stats.URL, _ = session.counters.url.Load().(string)
+	if connects := atomic.LoadUint64(&session.counters.connects); 1 < connects {
+		stats.Reconnects = connects - 1
+	}
+	for index, name := range rtspMethods {
+		if latency := atomic.LoadInt64(&session.counters.requestLatency[index]); 0 != latency {
+			stats.RequestLatency[name] = time.Duration(latency)
+		}
+	}
+	tracks, _ := session.counters.tracks.Load().([]*RtpParser)
+	for _, rtpParser := range tracks {
+		stats.Tracks = append(stats.Tracks, rtpParser.counters.snapshot(now))
+	}
+	return stats
+}
@@ -0,0 +1,138 @@
+package rtspclient
+
+import (
+	"encoding/binary"
+	"sync"
+	"testing"
+	"time"
+)
+
+func newTestStatsSession() *RtspClientSession {
+	session := NewRtspClientSession(func(rtspData *RtspData) {}, nil)
+	media := MediaSubsession{CodecName: "PCMA", PayloadFormat: 8, RtpTimestampFrequency: 8000}
+	rtpParser := newRtpParser(media)
+	rtpParser.counters.channelNum = 0
+	rtpParser.counters.codecName = media.CodecName
+	session.addTrack(0, rtpParser, media)
+	return session
+}
+
+func interleaved(channelNum int, packet []byte) []byte {
+	return append([]byte{'$', byte(channelNum), byte(len(packet) >> 8), byte(len(packet))}, packet...)
+}
+
+func TestSessionStats(t *testing.T) {
+	session := newTestStatsSession()
+	payload := make([]byte, 160)
+	// 3 is lost, 5 arrives late, 6 twice
+	for _, seq := range []uint16{0, 1, 2, 4, 6, 5, 6, 7} {
+		session.parsingRtp(interleaved(0, newTestRtpPacket(false, seq, uint32(seq)*160, payload)))
+	}
+	session.recordRequestLatency([]byte("DESCRIBE rtsp://camera/live RTSP/1.0\r\n"), 30*time.Millisecond)
+
+	stats := session.Stats()
+	if 1 != len(stats.Tracks) {
+		t.Fatalf("tracks %+v", stats.Tracks)
+	}
+	track := stats.Tracks[0]
+	if "PCMA" != track.CodecName || 8 != track.PacketsReceived || 8*(RtpHeaderLen+160) != track.BytesReceived {
+		t.Errorf("track %+v", track)
+	}
+	if 1 != track.PacketsLost || 1 != track.PacketsReordered || 1 != track.PacketsDuplicated {
+		t.Errorf("lost %d reordered %d duplicated %d", track.PacketsLost, track.PacketsReordered, track.PacketsDuplicated)
+	}
+	if 8 != track.FramesReceived || 0 >= track.LastFrameAge {
+		t.Errorf("frames %d age %v", track.FramesReceived, track.LastFrameAge)
+	}
+	if 30*time.Millisecond != stats.RequestLatency["DESCRIBE"] || 1 != len(stats.RequestLatency) {
+		t.Errorf("request latency %v", stats.RequestLatency)
+	}
+}
+
+func TestSessionStatsConcurrent(t *testing.T) {
+	session := newTestStatsSession()
+	var wait sync.WaitGroup
+	done := make(chan struct{})
+	wait.Add(1)
+	go func() {
+		defer wait.Done()
+		for {
+			select {
+			case <-done:
+				return
+			default:
+				session.Stats()
+			}
+		}
+	}()
+	for seq := uint16(0); seq < 1000; seq++ {
+		session.parsingRtp(interleaved(0, newTestRtpPacket(false, seq, uint32(seq)*160, []byte{1})))
+	}
+	close(done)
+	wait.Wait()
+	if packets := session.Stats().Tracks[0].PacketsReceived; 1000 != packets {
+		t.Errorf("packets %d", packets)
+	}
+}
+
+func TestTrackCountersRates(t *testing.T) {
+	counters := trackCounters{}
+	start := time.Unix(1000, 0)
+	for index := 0; index <= 50; index++ {
+		arrival := start.Add(time.Duration(index) * 20 * time.Millisecond)
+		counters.onPacket(125, arrival)
+		counters.onFrame(0 == index%25, arrival)
+	}
+	stats := counters.snapshot(start.Add(1100 * time.Millisecond))
+	if 50000 != stats.Bitrate || 50 != stats.FrameRate {
+		t.Errorf("bitrate %v frame rate %v", stats.Bitrate, stats.FrameRate)
+	}
+	if 500*time.Millisecond != stats.KeyframeInterval || 100*time.Millisecond != stats.LastFrameAge {
+		t.Errorf("keyframe interval %v age %v", stats.KeyframeInterval, stats.LastFrameAge)
+	}
+}
+
+func TestTrackCountersStalled(t *testing.T) {
+	counters := trackCounters{}
+	start := time.Unix(1000, 0)
+	for index := 0; index <= 50; index++ {
+		arrival := start.Add(time.Duration(index) * 20 * time.Millisecond)
+		counters.onPacket(125, arrival)
+		counters.onFrame(false, arrival)
+	}
+	// one packet since the last window closed, 2s ago
+	stats := counters.snapshot(start.Add(3 * time.Second))
+	if 500 != stats.Bitrate || 0.5 != stats.FrameRate {
+		t.Errorf("bitrate %v frame rate %v", stats.Bitrate, stats.FrameRate)
+	}
+	stats = counters.snapshot(start.Add(time.Hour))
+	if 1 < stats.Bitrate || 0.001 < stats.FrameRate {
+		t.Errorf("bitrate %v frame rate %v an hour later", stats.Bitrate, stats.FrameRate)
+	}
+}
+
+func TestDlrrRoundTrip(t *testing.T) {
+	sent := time.Unix(1000, 0)
+	now := sent.Add(300 * time.Millisecond)
+	rrtr := marshalRtcpReceiverReferenceTime(0xcafebabe, sent)
+	rtcp, err := ParseRtcp(rrtr)
+	if nil != err || 1 != len(rtcp.XRs) || rtcpXRReceiverReferenceTime != rtcp.XRs[0].Blocks[0].BlockType {
+		t.Fatalf("rrtr %+v %v", rtcp, err)
+	}
+	if !ntpTime(uint32(ntpTimestamp(sent)>>32), uint32(ntpTimestamp(sent))).Equal(sent) {
+		t.Errorf("ntp timestamp")
+	}
+
+	// the sender held it 100ms
+	dlrr := make([]byte, 12)
+	binary.BigEndian.PutUint32(dlrr, 0xcafebabe)
+	binary.BigEndian.PutUint32(dlrr[4:], uint32(ntpTimestamp(sent)>>16))
+	binary.BigEndian.PutUint32(dlrr[8:], 65536/10)
+	xrs := []RtcpXR{{Blocks: []RtcpXRBlock{{BlockType: rtcpXRDLRR, Data: dlrr}}}}
+	if roundTrip := dlrrRoundTrip(xrs, 0xcafebabe, now); roundTrip < 199*time.Millisecond || 201*time.Millisecond < roundTrip {
+		t.Errorf("round trip %v", roundTrip)
+	}
+	if roundTrip := dlrrRoundTrip(xrs, 1, now); 0 != roundTrip {
+		t.Errorf("round trip of another ssrc %v", roundTrip)
+	}
+}
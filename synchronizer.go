@@ -0,0 +1,128 @@
+package rtspclient
+
+import (
+	"container/heap"
+	"sync"
+	"time"
+)
+
+// FrameSynchronizer releases the frames of all tracks of a session in the
+// order of their wall-clock Time. A frame is held until it is latency older
+// than the newest frame, or has waited latency itself. Frames without a Time
+// pass right through. Use Push as the data handler of the session.
+type FrameSynchronizer struct {
+	latency  time.Duration
+	handler  func(*RtspData)
+	mutex    sync.Mutex
+	frames   syncFrameQueue
+	newest   time.Time
+	sequence uint64
+	timer    *time.Timer
+	closed   bool
+	now      func() time.Time
+}
+
+type syncFrame struct {
+	data     *RtspData
+	received time.Time
+	sequence uint64 // keeps frames of the same Time in arrival order
+}
+
+type syncFrameQueue []syncFrame
+
+func (queue syncFrameQueue) Len() int { return len(queue) }
+
+func (queue syncFrameQueue) Less(i, j int) bool {
+	if queue[i].data.Time.Equal(queue[j].data.Time) {
+		return queue[i].sequence < queue[j].sequence
+	}
+	return queue[i].data.Time.Before(queue[j].data.Time)
+}
+
+func (queue syncFrameQueue) Swap(i, j int) { queue[i], queue[j] = queue[j], queue[i] }
+
+func (queue *syncFrameQueue) Push(frame interface{}) { *queue = append(*queue, frame.(syncFrame)) }
+
+func (queue *syncFrameQueue) Pop() interface{} {
+	old := *queue
+	frame := old[len(old)-1]
+	old[len(old)-1] = syncFrame{}
+	*queue = old[:len(old)-1]
+	return frame
+}
+
+// NewFrameSynchronizer creates a synchronizer that hands released frames to
+// handler. The handler runs one frame at a time and must not call Push.
+func NewFrameSynchronizer(latency time.Duration, handler func(*RtspData)) *FrameSynchronizer {
+	return &FrameSynchronizer{
+		latency: latency,
+		handler: handler,
+		now:     time.Now,
+	}
+}
+
+// Push queues a frame.
+func (synchronizer *FrameSynchronizer) Push(rtspData *RtspData) {
+	synchronizer.mutex.Lock()
+	defer synchronizer.mutex.Unlock()
+
+	if synchronizer.closed || rtspData.Time.IsZero() {
+		synchronizer.handler(rtspData)
+		return
+	}
+	synchronizer.sequence++
+	heap.Push(&synchronizer.frames, syncFrame{data: rtspData, received: synchronizer.now(), sequence: synchronizer.sequence})
+	if rtspData.Time.After(synchronizer.newest) {
+		synchronizer.newest = rtspData.Time
+	}
+	synchronizer.release(false)
+}
+
+// Flush releases all queued frames.
+func (synchronizer *FrameSynchronizer) Flush() {
+	synchronizer.mutex.Lock()
+	defer synchronizer.mutex.Unlock()
+	synchronizer.release(true)
+}
+
+// Close releases all queued frames, later frames pass right through.
+func (synchronizer *FrameSynchronizer) Close() {
+	synchronizer.mutex.Lock()
+	defer synchronizer.mutex.Unlock()
+	synchronizer.release(true)
+	synchronizer.closed = true
+}
+
+func (synchronizer *FrameSynchronizer) onTimer() {
+	synchronizer.mutex.Lock()
+	defer synchronizer.mutex.Unlock()
+	synchronizer.release(false)
+}
+
+// release hands the due frames to the handler and arms the timer for the next
+// one. Call it locked.
+func (synchronizer *FrameSynchronizer) release(all bool) {
+	now := synchronizer.now()
+	deadline := synchronizer.newest.Add(-synchronizer.latency)
+	for 0 < len(synchronizer.frames) {
+		head := synchronizer.frames[0]
+		if !all && head.data.Time.After(deadline) && now.Sub(head.received) < synchronizer.latency {
+			break
+		}
+		heap.Pop(&synchronizer.frames)
+		synchronizer.handler(head.data)
+	}
+
+	if 0 == len(synchronizer.frames) {
+		if nil != synchronizer.timer {
+			synchronizer.timer.Stop()
+		}
+		return
+	}
+	wait := synchronizer.latency - now.Sub(synchronizer.frames[0].received)
+	if nil == synchronizer.timer {
+		synchronizer.timer = time.AfterFunc(wait, synchronizer.onTimer)
+	} else {
+		synchronizer.timer.Reset(wait)
+	}
+}
@@ -0,0 +1,72 @@
+package rtspclient
+
+import (
+	"testing"
+	"time"
+)
+
+func TestFrameSynchronizerOrder(t *testing.T) {
+	var released []int
+	synchronizer := NewFrameSynchronizer(100*time.Millisecond, func(rtspData *RtspData) {
+		released = append(released, rtspData.ChannelNum)
+	})
+	now := time.Unix(1000, 0)
+	synchronizer.now = func() time.Time { return now }
+	defer synchronizer.Close()
+
+	base := time.Unix(2000, 0)
+	push := func(channelNum int, offset time.Duration) {
+		synchronizer.Push(&RtspData{ChannelNum: channelNum, Time: base.Add(offset)})
+	}
+	// video runs ahead of audio by 30ms
+	push(0, 0)
+	push(0, 40*time.Millisecond)
+	push(2, -30*time.Millisecond)
+	push(2, -10*time.Millisecond)
+	push(2, 10*time.Millisecond)
+	if 0 != len(released) {
+		t.Fatalf("released early %v", released)
+	}
+	// 100ms past the first frames
+	push(0, 80*time.Millisecond)
+	push(0, 120*time.Millisecond)
+	expected := []int{2, 2, 0, 2}
+	if len(expected) != len(released) {
+		t.Fatalf("released %v", released)
+	}
+	for index := range expected {
+		if expected[index] != released[index] {
+			t.Fatalf("released %v", released)
+		}
+	}
+
+	// frames without a wall-clock time pass right through
+	synchronizer.Push(&RtspData{ChannelNum: 4})
+	if 4 != released[len(released)-1] {
+		t.Errorf("released %v", released)
+	}
+
+	synchronizer.Flush()
+	if 8 != len(released) || 0 != released[7] {
+		t.Errorf("released after flush %v", released)
+	}
+}
+
+func TestFrameSynchronizerLatency(t *testing.T) {
+	releasedChan := make(chan *RtspData, 1)
+	synchronizer := NewFrameSynchronizer(20*time.Millisecond, func(rtspData *RtspData) {
+		releasedChan <- rtspData
+	})
+	defer synchronizer.Close()
+
+	pushed := time.Now()
+	synchronizer.Push(&RtspData{Time: time.Unix(2000, 0)})
+	select {
+	case <-releasedChan:
+		if waited := time.Since(pushed); waited < 20*time.Millisecond {
+			t.Errorf("released after %v", waited)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("frame not released")
+	}
+}
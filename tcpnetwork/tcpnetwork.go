@@ -4,8 +4,9 @@ import (
 	"bufio"
 	"bytes"
 	"errors"
-	"log"
 	"net"
+	"sync"
+	"sync/atomic"
 	"time"
 )
 
@@ -40,7 +41,9 @@ const (
 type Connection struct {
 	conn                net.Conn
 	connRW              *bufio.ReadWriter
-	status              int
+	status              int32
+	quit                chan struct{}
+	quitOnce            sync.Once
 	sendMsgQueue        chan []byte
 	sendBufferSize      int
 	sendTimeoutSec      int
@@ -48,8 +51,25 @@ type Connection struct {
 	eventHandler        func(*ConnEvent)
 	streamProtocol      IStreamProtocol
 	maxReadBufferLength int
+	logger              Logger
 }
 
+// Logger receives the log records of a connection as a message and key value
+// pairs. *slog.Logger satisfies it.
+type Logger interface {
+	Debug(msg string, args ...interface{})
+	Info(msg string, args ...interface{})
+	Warn(msg string, args ...interface{})
+	Error(msg string, args ...interface{})
+}
+
+type discardLogger struct{}
+
+func (discardLogger) Debug(msg string, args ...interface{}) {}
+func (discardLogger) Info(msg string, args ...interface{})  {}
+func (discardLogger) Warn(msg string, args ...interface{})  {}
+func (discardLogger) Error(msg string, args ...interface{}) {}
+
 // ConnEvent TCP connnection event
 type ConnEvent struct {
 	EventType int
@@ -71,27 +91,37 @@ func NewConnection(c net.Conn, sendBufferSize int, eventHandler func(*ConnEvent)
 		conn:                c,
 		connRW:              bufio.NewReadWriter(bufio.NewReaderSize(c, connConfMaxReadBufferLength), bufio.NewWriterSize(c, connConfMaxReadBufferLength)),
 		status:              ConnStatusNone,
+		quit:                make(chan struct{}),
 		sendMsgQueue:        make(chan []byte, sendBufferSize),
 		sendBufferSize:      sendBufferSize,
 		sendTimeoutSec:      connConfDefaultSendTimeoutSec,
 		maxReadBufferLength: connConfMaxReadBufferLength,
 		eventHandler:        eventHandler,
+		logger:              discardLogger{},
+	}
+}
+
+// SetLogger sets the logger of the connection, records are discarded by
+// default. Set it before Run.
+func (connection *Connection) SetLogger(logger Logger) {
+	if nil == logger {
+		logger = discardLogger{}
 	}
+	connection.logger = logger
 }
 
 // directly close, packages in queue will not be sent
 func (connection *Connection) close() {
-	if ConnStatusConnected != connection.status {
+	if !atomic.CompareAndSwapInt32(&connection.status, ConnStatusConnected, ConnStatusDisconnected) {
 		return
 	}
 
 	connection.conn.Close()
-	connection.status = ConnStatusDisconnected
 }
 
-// Close close tcp connection
+// Close close tcp connection, packages already in queue are sent first
 func (connection *Connection) Close() {
-	if connection.status != ConnStatusConnected {
+	if ConnStatusConnected != connection.GetStatus() {
 		return
 	}
 
@@ -100,18 +130,22 @@ func (connection *Connection) Close() {
 		{
 			// nothing
 		}
+	case <-connection.quit:
+		{
+			// routine already quit
+		}
 	case <-time.After(time.Duration(connection.sendTimeoutSec) * time.Second):
 		{
 			// timeout, close the connection
 			connection.close()
-			log.Printf("Conn send message timeout, close it")
+			connection.logger.Warn("conn send message timeout, close it")
 		}
 	}
 }
 
 func (connection *Connection) pushEvent(eventType int, data []byte) {
 	if nil == connection.eventHandler {
-		log.Println("nil event queue")
+		connection.logger.Warn("nil event queue")
 		return
 	}
 	connection.eventHandler(newConnEvent(eventType, connection, data))
@@ -119,11 +153,7 @@ func (connection *Connection) pushEvent(eventType int, data []byte) {
 
 // GetStatus get connection status
 func (connection *Connection) GetStatus() int {
-	return connection.status
-}
-
-func (connection *Connection) setStatus(status int) {
-	connection.status = status
+	return int(atomic.LoadInt32(&connection.status))
 }
 
 // SetReadTimeoutSec set read time out
@@ -142,7 +172,7 @@ func (connection *Connection) SetStreamProtocol(streamProtocol IStreamProtocol)
 }
 
 func (connection *Connection) sendRaw(msg []byte) {
-	if connection.status != ConnStatusConnected {
+	if ConnStatusConnected != connection.GetStatus() {
 		return
 	}
 
@@ -151,18 +181,22 @@ func (connection *Connection) sendRaw(msg []byte) {
 		{
 			// nothing
 		}
+	case <-connection.quit:
+		{
+			// routine already quit, drop it
+		}
 	case <-time.After(time.Duration(connection.sendTimeoutSec) * time.Second):
 		{
 			// timeout, close the connection
 			connection.close()
-			log.Printf("Conn send message timeout, close it")
+			connection.logger.Warn("conn send message timeout, close it")
 		}
 	}
 }
 
 // Send send data
 func (connection *Connection) Send(msg []byte, needCopy bool) {
-	if connection.status != ConnStatusConnected {
+	if ConnStatusConnected != connection.GetStatus() {
 		return
 	}
 
@@ -176,40 +210,42 @@ func (connection *Connection) Send(msg []byte, needCopy bool) {
 	connection.sendRaw(buf)
 }
 
-// Run a routine to process connection connection
+// Run a routine to process connection connection. The connection is
+// connected when Run returns, so data sent right after is not lost.
 func (connection *Connection) Run() {
+	if !atomic.CompareAndSwapInt32(&connection.status, ConnStatusNone, ConnStatusConnected) {
+		return
+	}
 	go connection.routineMain()
 }
 
 func (connection *Connection) routineMain() {
 	defer func() {
 		// routine end
-		log.Printf("Rotine of connection quit")
+		connection.logger.Debug("routine of connection quit")
 		e := recover()
 		if e != nil {
-			log.Println("Panic: ", e)
+			connection.logger.Error("connection panic", "panic", e)
 		}
 
 		// close the connection
 		connection.close()
 
-		// free channel
-		close(connection.sendMsgQueue)
-		connection.sendMsgQueue = make(chan []byte, connection.sendBufferSize)
+		// stop the send routine, the queue stays open for late senders
+		connection.quitOnce.Do(func() { close(connection.quit) })
 
 		// post event
 		connection.pushEvent(ConnEventDisconnected, nil)
 	}()
 
 	if nil == connection.streamProtocol {
-		log.Println("Nil stream protocol")
+		connection.logger.Error("nil stream protocol")
 		return
 	}
 	connection.streamProtocol.Init()
 
 	// connected
 	connection.pushEvent(ConnEventConnected, nil)
-	connection.status = ConnEventConnected
 
 	go connection.routineSend()
 	connection.routineRead()
@@ -217,20 +253,19 @@ func (connection *Connection) routineMain() {
 
 func (connection *Connection) routineSend() error {
 	defer func() {
-		log.Println("Connection send log return")
+		connection.logger.Debug("connection send routine return")
 	}()
 
 	for {
 		select {
-		case sendMsg, ok := <-connection.sendMsgQueue:
+		case <-connection.quit:
+			{
+				return nil
+			}
+		case sendMsg := <-connection.sendMsgQueue:
 			{
-				if !ok {
-					// channel closed, quit
-					return nil
-				}
-
 				if nil == sendMsg {
-					log.Println("User disconnect")
+					connection.logger.Debug("user disconnect")
 					connection.close()
 					return nil
 				}
@@ -241,14 +276,14 @@ func (connection *Connection) routineSend() error {
 					// write header first
 					_, err = connection.connRW.Write(headerBytes)
 					if err != nil {
-						log.Println("Conn write error:", err)
+						connection.logger.Warn("conn write error", "error", err)
 						return err
 					}
 					connection.connRW.Flush()
 				}
 				_, err = connection.connRW.Write(sendMsg)
 				if err != nil {
-					log.Println("Conn write error:", err)
+					connection.logger.Warn("conn write error", "error", err)
 					return err
 				}
 				connection.connRW.Flush()
@@ -264,7 +299,7 @@ func (connection *Connection) routineRead() error {
 	for {
 		data, err := connection.unpack(buf)
 		if err != nil {
-			log.Println("Conn read error: ", err)
+			connection.logger.Debug("conn read error", "error", err)
 			return err
 		}
 
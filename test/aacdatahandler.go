@@ -0,0 +1,34 @@
+package main
+
+import (
+	"strings"
+
+	"github.com/NodeBoy2/rtspclient"
+)
+
+type AacDataHandle struct {
+	config *rtspclient.AACConfig
+}
+
+func (dataHandler *AacDataHandle) SetMediaSubsession(media rtspclient.MediaSubsession) {
+	if "MP4A-LATM" == strings.ToUpper(media.CodecName) {
+		latmConfig, err := rtspclient.ParseLATMConfigString(media.Fmtp["config"])
+		if nil == err {
+			dataHandler.config = latmConfig.AAC
+		}
+		return
+	}
+	dataHandler.config, _ = rtspclient.ParseAACConfigString(media.Fmtp["config"])
+}
+
+func (dataHandler *AacDataHandle) GetHeader() []byte {
+	return make([]byte, 0)
+}
+
+func (dataHandler *AacDataHandle) ParsingData(src []byte) []byte {
+	if nil == dataHandler.config {
+		return src
+	}
+	dst := dataHandler.config.ADTSHeader(len(src))
+	return append(dst, src...)
+}
@@ -1,45 +1,27 @@
 package main
 
 import (
-	"encoding/base64"
-	"strings"
-
 	"github.com/NodeBoy2/rtspclient"
 )
 
 type H264DataHandle struct {
-	byteSps []byte
-	bytePps []byte
+	parameterSets *rtspclient.ParameterSets
 }
 
 func (dataHandler *H264DataHandle) SetMediaSubsession(media rtspclient.MediaSubsession) {
-	spsInfo := media.Fmtp["sprop-parameter-sets"]
-	if "" == spsInfo {
-		return
-	}
-	spsPps := strings.Split(spsInfo, ",")
-	var sps, pps string
-	if len(spsPps) == 1 {
-		sps = spsPps[0]
-	} else if len(spsPps) == 2 {
-		sps = spsPps[0]
-		pps = spsPps[1]
-	}
-
-	dataHandler.byteSps, _ = base64.StdEncoding.DecodeString(sps)
-	dataHandler.bytePps, _ = base64.StdEncoding.DecodeString(pps)
+	dataHandler.parameterSets = rtspclient.ParseH264ParameterSets(media.Fmtp)
 }
 
 func (dataHandler *H264DataHandle) GetHeader() []byte {
-	if len(dataHandler.byteSps) == 0 {
+	if nil == dataHandler.parameterSets || len(dataHandler.parameterSets.SPS) == 0 {
 		return nil
 	}
 	headerByte := []byte{0x00, 0x00, 0x00, 0x01}
 	header := make([]byte, 0)
-	header = append(header, headerByte...)
-	header = append(header, dataHandler.byteSps...)
-	header = append(header, headerByte...)
-	header = append(header, dataHandler.bytePps...)
+	for _, nalu := range dataHandler.parameterSets.NALUs() {
+		header = append(header, headerByte...)
+		header = append(header, nalu...)
+	}
 	return header
 }
 
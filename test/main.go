@@ -4,6 +4,7 @@ import (
 	"fmt"
 	"os"
 	"strconv"
+	"strings"
 	"sync"
 	"time"
 
@@ -25,7 +26,7 @@ func (handler *RtspHandler) WriteMediaHeader(session *rtspclient.RtspClientSessi
 	handler.lock.Lock()
 	defer handler.lock.Unlock()
 	handler.mediaHandler = make(map[int]MediaDataHandler)
-	for index, media := range session.RtpMediaMap {
+	for index, media := range session.GetMediaMap() {
 		file, err := os.OpenFile("D://test//"+strconv.Itoa(index), os.O_WRONLY|os.O_CREATE|os.O_TRUNC, os.ModeExclusive)
 		defer file.Close()
 		if nil != err {
@@ -37,6 +38,8 @@ func (handler *RtspHandler) WriteMediaHeader(session *rtspclient.RtspClientSessi
 			handler.mediaHandler[index] = &H264DataHandle{}
 		} else if "H265" == media.CodecName {
 			handler.mediaHandler[index] = &H265DataHandle{}
+		} else if "MPEG4-GENERIC" == strings.ToUpper(media.CodecName) || "MP4A-LATM" == strings.ToUpper(media.CodecName) {
+			handler.mediaHandler[index] = &AacDataHandle{}
 		} else {
 			handler.mediaHandler[index] = &DefaultDataHandle{}
 		}
@@ -0,0 +1,246 @@
+package rtspclient
+
+import (
+	"encoding/binary"
+	"errors"
+)
+
+const (
+	tsPacketLen = 188
+	tsSyncByte  = 0x47
+	tsPatPid    = 0x0000
+	tsNullPid   = 0x1fff
+)
+
+// MP2TStreamInfo identifies the elementary stream a frame was demuxed from.
+type MP2TStreamInfo struct {
+	Pid        int
+	StreamType int    // stream_type from the PMT
+	CodecName  string // "H264", "H265", "AAC"..., empty if unknown
+	PTS        int64  // 90 kHz, -1 if absent
+	DTS        int64  // 90 kHz, -1 if absent
+}
+
+func tsStreamCodecName(streamType int) string {
+	switch streamType {
+	case 0x01, 0x02:
+		return "MPV"
+	case 0x03, 0x04:
+		return "MPA"
+	case 0x0f:
+		return "AAC"
+	case 0x10:
+		return "MP4V-ES"
+	case 0x11:
+		return "MP4A-LATM"
+	case 0x15:
+		return "METADATA"
+	case 0x1b:
+		return "H264"
+	case 0x24:
+		return "H265"
+	}
+	return ""
+}
+
+type tsStream struct {
+	pid        int
+	streamType int
+	continuity int // last continuity_counter, -1 before the first packet
+	pesBuf     []byte
+	pesLen     int // expected PES length, 0 if unbounded
+	started    bool
+}
+
+// tsDemuxer splits an MPEG transport stream (ISO/IEC 13818-1) of a single
+// program into PES payloads.
+type tsDemuxer struct {
+	pmtPid  int
+	streams map[int]*tsStream
+	onFrame func(info MP2TStreamInfo, data []byte)
+}
+
+func newTsDemuxer(onFrame func(info MP2TStreamInfo, data []byte)) *tsDemuxer {
+	return &tsDemuxer{
+		pmtPid:  -1,
+		streams: make(map[int]*tsStream),
+		onFrame: onFrame,
+	}
+}
+
+// pushPacket demuxes one 188 byte TS packet.
+func (demuxer *tsDemuxer) pushPacket(packet []byte) error {
+	if len(packet) != tsPacketLen || tsSyncByte != packet[0] {
+		return errors.New("ts: lost sync")
+	}
+	if 0 != packet[1]&0x80 {
+		return errors.New("ts: transport error indicator set")
+	}
+	payloadUnitStart := 0 != packet[1]&0x40
+	pid := int(binary.BigEndian.Uint16(packet[1:3]) & 0x1fff)
+	adaptationFieldControl := (packet[3] >> 4) & 0x03
+	continuity := int(packet[3] & 0x0f)
+
+	pos := 4
+	if 0 != adaptationFieldControl&0x02 {
+		pos += 1 + int(packet[4])
+	}
+	if 0 == adaptationFieldControl&0x01 || pos >= tsPacketLen {
+		return nil
+	}
+	payload := packet[pos:]
+
+	switch {
+	case tsPatPid == pid:
+		demuxer.parsingPat(payloadUnitStart, payload)
+	case demuxer.pmtPid == pid:
+		demuxer.parsingPmt(payloadUnitStart, payload)
+	case tsNullPid == pid:
+	default:
+		stream, ok := demuxer.streams[pid]
+		if ok {
+			demuxer.pushPes(stream, payloadUnitStart, continuity, payload)
+		}
+	}
+	return nil
+}
+
+// psiSection returns the section starting in a PSI payload, CRC excluded.
+// Sections spanning several packets are not supported.
+func psiSection(payloadUnitStart bool, payload []byte) []byte {
+	if !payloadUnitStart || len(payload) < 1 {
+		return nil
+	}
+	pointer := int(payload[0])
+	payload = payload[1:]
+	if len(payload) < pointer+3 {
+		return nil
+	}
+	section := payload[pointer:]
+	sectionLength := int(binary.BigEndian.Uint16(section[1:3]) & 0x0fff)
+	if len(section) < 3+sectionLength || sectionLength < 9 {
+		return nil
+	}
+	return section[:3+sectionLength-4]
+}
+
+func (demuxer *tsDemuxer) parsingPat(payloadUnitStart bool, payload []byte) {
+	section := psiSection(payloadUnitStart, payload)
+	if nil == section || 0x00 != section[0] {
+		return
+	}
+	for entry := section[8:]; len(entry) >= 4; entry = entry[4:] {
+		programNumber := binary.BigEndian.Uint16(entry[0:2])
+		if 0 == programNumber {
+			// network PID
+			continue
+		}
+		demuxer.pmtPid = int(binary.BigEndian.Uint16(entry[2:4]) & 0x1fff)
+		return
+	}
+}
+
+func (demuxer *tsDemuxer) parsingPmt(payloadUnitStart bool, payload []byte) {
+	section := psiSection(payloadUnitStart, payload)
+	if nil == section || 0x02 != section[0] || len(section) < 12 {
+		return
+	}
+	programInfoLength := int(binary.BigEndian.Uint16(section[10:12]) & 0x0fff)
+	if len(section) < 12+programInfoLength {
+		return
+	}
+
+	streams := make(map[int]*tsStream)
+	for entry := section[12+programInfoLength:]; len(entry) >= 5; {
+		streamType := int(entry[0])
+		pid := int(binary.BigEndian.Uint16(entry[1:3]) & 0x1fff)
+		esInfoLength := int(binary.BigEndian.Uint16(entry[3:5]) & 0x0fff)
+		if stream, ok := demuxer.streams[pid]; ok && stream.streamType == streamType {
+			streams[pid] = stream
+		} else {
+			streams[pid] = &tsStream{pid: pid, streamType: streamType, continuity: -1}
+		}
+		if len(entry) < 5+esInfoLength {
+			break
+		}
+		entry = entry[5+esInfoLength:]
+	}
+	demuxer.streams = streams
+}
+
+func (demuxer *tsDemuxer) pushPes(stream *tsStream, payloadUnitStart bool, continuity int, payload []byte) {
+	if -1 != stream.continuity && continuity == stream.continuity {
+		// duplicate packet
+		return
+	}
+	lost := -1 != stream.continuity && continuity != (stream.continuity+1)&0x0f
+	stream.continuity = continuity
+
+	if payloadUnitStart {
+		demuxer.flushPes(stream)
+		stream.started = true
+		stream.pesLen = 0
+		if len(payload) >= 6 {
+			if packetLength := int(binary.BigEndian.Uint16(payload[4:6])); 0 != packetLength {
+				stream.pesLen = 6 + packetLength
+			}
+		}
+	} else if lost {
+		// drop the PES with the missing packet
+		stream.started = false
+		stream.pesBuf = stream.pesBuf[:0]
+	}
+	if !stream.started {
+		return
+	}
+
+	if len(stream.pesBuf)+len(payload) > MaxPayloadLength {
+		stream.started = false
+		stream.pesBuf = stream.pesBuf[:0]
+		return
+	}
+	stream.pesBuf = append(stream.pesBuf, payload...)
+	if 0 != stream.pesLen && len(stream.pesBuf) >= stream.pesLen {
+		stream.pesBuf = stream.pesBuf[:stream.pesLen]
+		demuxer.flushPes(stream)
+	}
+}
+
+// flushPes delivers the buffered PES of a stream.
+func (demuxer *tsDemuxer) flushPes(stream *tsStream) {
+	pes := stream.pesBuf
+	stream.pesBuf = stream.pesBuf[:0]
+	if !stream.started {
+		return
+	}
+	stream.started = false
+
+	if len(pes) < 9 || 0x00 != pes[0] || 0x00 != pes[1] || 0x01 != pes[2] {
+		return
+	}
+	info := MP2TStreamInfo{
+		Pid:        stream.pid,
+		StreamType: stream.streamType,
+		CodecName:  tsStreamCodecName(stream.streamType),
+		PTS:        -1,
+		DTS:        -1,
+	}
+	ptsDtsFlags := pes[7] >> 6
+	headerDataLength := int(pes[8])
+	if len(pes) < 9+headerDataLength {
+		return
+	}
+	if 0 != ptsDtsFlags&0x02 && 5 <= headerDataLength {
+		info.PTS = parsingPesTimestamp(pes[9:14])
+		info.DTS = info.PTS
+		if 0 != ptsDtsFlags&0x01 && 10 <= headerDataLength {
+			info.DTS = parsingPesTimestamp(pes[14:19])
+		}
+	}
+	demuxer.onFrame(info, pes[9+headerDataLength:])
+}
+
+func parsingPesTimestamp(data []byte) int64 {
+	return int64(data[0]>>1&0x07)<<30 | int64(data[1])<<22 | int64(data[2]>>1)<<15 |
+		int64(data[3])<<7 | int64(data[4]>>1)
+}
@@ -0,0 +1,107 @@
+package rtspclient
+
+import (
+	"bytes"
+	"testing"
+)
+
+func TestVp8RtpParser(t *testing.T) {
+	rtpParser := newRtpParser(MediaSubsession{CodecName: "VP8", RtpTimestampFrequency: 90000})
+	// X, I with a 15 bit picture ID, then the key frame header
+	first := []byte{0x90, 0x80, 0x81, 0x23, 0x10, 0x02, 0x00}
+	second := []byte{0x80, 0x80, 0x81, 0x23, 0x03, 0x04}
+	frames := parsingTestPackets(rtpParser, newTestRtpPacket(false, 10, 0, first), newTestRtpPacket(true, 11, 0, second))
+	if len(frames) != 1 || !frames[0].IsKeyFrame || !bytes.Equal(frames[0].Data, []byte{0x10, 0x02, 0x00, 0x03, 0x04}) {
+		t.Fatalf("vp8 key frame not reassembled: %v", frames)
+	}
+
+	// a lost packet drops the frame
+	frames = parsingTestPackets(rtpParser, newTestRtpPacket(false, 12, 0, []byte{0x10, 0x01}), newTestRtpPacket(true, 14, 0, []byte{0x00, 0x02}))
+	if len(frames) != 0 {
+		t.Errorf("got %d frames from an incomplete frame", len(frames))
+	}
+}
+
+func TestVp9RtpParser(t *testing.T) {
+	rtpParser := newRtpParser(MediaSubsession{CodecName: "VP9", RtpTimestampFrequency: 90000})
+	// I with a 7 bit picture ID, B on the first packet, E on the last one
+	frames := parsingTestPackets(rtpParser,
+		newTestRtpPacket(false, 1, 0, []byte{0x88, 0x05, 0x82, 0x49}),
+		newTestRtpPacket(true, 2, 0, []byte{0x84, 0x05, 0x83}))
+	if len(frames) != 1 || !frames[0].IsKeyFrame || !bytes.Equal(frames[0].Data, []byte{0x82, 0x49, 0x83}) {
+		t.Fatalf("vp9 key frame not reassembled: %v", frames)
+	}
+
+	// P set: inter predicted
+	frames = parsingTestPackets(rtpParser, newTestRtpPacket(true, 3, 0, []byte{0xcc, 0x06, 0x86}))
+	if len(frames) != 1 || frames[0].IsKeyFrame {
+		t.Errorf("vp9 inter frame not delivered")
+	}
+}
+
+func TestAv1RtpParser(t *testing.T) {
+	rtpParser := newRtpParser(MediaSubsession{CodecName: "AV1", RtpTimestampFrequency: 90000})
+	sequenceHeader := []byte{0x08, 0x01, 0x02, 0x03}
+	frameObu := []byte{0x30, 0x11, 0x12, 0x13, 0x14, 0x15}
+
+	// W=2 with N: sequence header, then the start of the frame OBU
+	first := []byte{0x68, byte(len(sequenceHeader))}
+	first = append(first, sequenceHeader...)
+	first = append(first, frameObu[:3]...)
+	// Z, W=1: rest of the frame OBU
+	second := append([]byte{0x90}, frameObu[3:]...)
+
+	frames := parsingTestPackets(rtpParser, newTestRtpPacket(false, 7, 0, first), newTestRtpPacket(true, 8, 0, second))
+	if len(frames) != 1 || !frames[0].IsKeyFrame {
+		t.Fatalf("av1 temporal unit not reassembled: %v", frames)
+	}
+	expected := []byte{0x12, 0x00, 0x0a, 0x03, 0x01, 0x02, 0x03, 0x32, 0x05, 0x11, 0x12, 0x13, 0x14, 0x15}
+	if !bytes.Equal(frames[0].Data, expected) {
+		t.Errorf("temporal unit %x, expected %x", frames[0].Data, expected)
+	}
+}
+
+func TestMp4vRtpParser(t *testing.T) {
+	config := "000001b001" + "0000012000c488800cd0b04241"
+	media := MediaSubsession{CodecName: "MP4V-ES", RtpTimestampFrequency: 90000, Fmtp: map[string]string{"config": config}}
+	rtpParser := newRtpParser(media)
+	vol := rtpParser.rtpSourceHandler.(*Mp4vRtpParser).Config()
+	if nil == vol || vol.ProfileLevel != 1 || vol.Width != 352 || vol.Height != 288 || vol.TimeIncrementResolution != 25 {
+		t.Fatalf("config not parsed: %+v", vol)
+	}
+
+	// I-VOP split in two packets gets the config in front
+	frames := parsingTestPackets(rtpParser,
+		newTestRtpPacket(false, 1, 0, []byte{0x00, 0x00, 0x01, 0xb6, 0x10, 0x20}),
+		newTestRtpPacket(true, 2, 0, []byte{0x30, 0x40}))
+	if len(frames) != 1 || !frames[0].IsKeyFrame {
+		t.Fatalf("i-vop not reassembled: %v", frames)
+	}
+	expected := append(vol.Raw, 0x00, 0x00, 0x01, 0xb6, 0x10, 0x20, 0x30, 0x40)
+	if !bytes.Equal(frames[0].Data, expected) {
+		t.Errorf("frame %x, expected %x", frames[0].Data, expected)
+	}
+
+	// P-VOP
+	frames = parsingTestPackets(rtpParser, newTestRtpPacket(true, 3, 3600, []byte{0x00, 0x00, 0x01, 0xb6, 0x50, 0x01}))
+	if len(frames) != 1 || frames[0].IsKeyFrame || len(frames[0].Data) != 6 {
+		t.Errorf("p-vop not delivered: %v", frames)
+	}
+}
+
+func TestH263RtpParser(t *testing.T) {
+	rtpParser := newRtpParser(MediaSubsession{CodecName: "H263-1998", RtpTimestampFrequency: 90000})
+	// P set: the two zero bytes of the picture start code are restored
+	frames := parsingTestPackets(rtpParser,
+		newTestRtpPacket(false, 1, 0, []byte{0x04, 0x00, 0x80, 0x06, 0x08}),
+		newTestRtpPacket(true, 2, 0, []byte{0x00, 0x00, 0x11}))
+	if len(frames) != 1 || !frames[0].IsKeyFrame || !bytes.Equal(frames[0].Data, []byte{0x00, 0x00, 0x80, 0x06, 0x08, 0x11}) {
+		t.Fatalf("intra picture not reassembled: %v", frames)
+	}
+
+	// V set with a VRC byte, inter picture
+	frames = parsingTestPackets(rtpParser, newTestRtpPacket(true, 3, 3000, []byte{0x06, 0x00, 0x00, 0x80, 0x0a, 0x0a}))
+	if len(frames) != 1 || frames[0].IsKeyFrame || !bytes.Equal(frames[0].Data, []byte{0x00, 0x00, 0x80, 0x0a, 0x0a}) {
+		t.Errorf("inter picture not delivered: %v", frames)
+	}
+}
@@ -0,0 +1,80 @@
+package rtspclient
+
+// Vp8RtpParser depacketizes VP8 (RFC 7741).
+type Vp8RtpParser struct {
+	assembler  frameAssembler
+	isKeyFrame bool
+}
+
+// vp8DescriptorLen returns the size of the VP8 payload descriptor, or 0 if
+// the payload is too short.
+func vp8DescriptorLen(rtpData []byte) int {
+	if len(rtpData) < 1 {
+		return 0
+	}
+	pos := 1
+	if 0 != rtpData[0]&0x80 {
+		// X: extended control bits
+		if len(rtpData) < pos+1 {
+			return 0
+		}
+		extension := rtpData[pos]
+		pos++
+		if 0 != extension&0x80 {
+			// I: PictureID, 15 bits if M is set
+			if len(rtpData) < pos+1 {
+				return 0
+			}
+			if 0 != rtpData[pos]&0x80 {
+				pos += 2
+			} else {
+				pos++
+			}
+		}
+		if 0 != extension&0x40 {
+			// L: TL0PICIDX
+			pos++
+		}
+		if 0 != extension&0x30 {
+			// T or K: TID/Y/KEYIDX
+			pos++
+		}
+	}
+	if len(rtpData) <= pos {
+		return 0
+	}
+	return pos
+}
+
+func (rtpParser *Vp8RtpParser) SplitHeader(src []byte) (isCompletesFrame bool, header []byte, payload []byte) {
+	rtpData := src[RtpHeaderLen:]
+	seq := rtpSequence(src)
+	descriptorLen := vp8DescriptorLen(rtpData)
+	if 0 == descriptorLen {
+		rtpParser.assembler.started = false
+		return false, src, src[len(src):]
+	}
+
+	// S set with partition index 0 starts a frame
+	if 0x10 == rtpData[0]&0x17 {
+		rtpParser.assembler.begin(seq)
+		// P bit of the VP8 frame header is 0 for key frames
+		rtpParser.isKeyFrame = 0 == rtpData[descriptorLen]&0x01
+	}
+	rtpParser.assembler.push(seq, rtpData[descriptorLen:])
+
+	if 0 == src[1]&0x80 {
+		return false, src, src[len(src):]
+	}
+	frame := rtpParser.assembler.finish()
+	return true, src[:RtpHeaderLen], frame
+}
+
+func (rtpParser *Vp8RtpParser) ParsingRtp(header []byte, payload []byte) (naluHeaderSize int, naluSize int) {
+	return 0, len(payload)
+}
+
+// FrameInfo marks key frames.
+func (rtpParser *Vp8RtpParser) FrameInfo(data *RtspData) {
+	data.IsKeyFrame = rtpParser.isKeyFrame
+}
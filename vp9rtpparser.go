@@ -0,0 +1,128 @@
+package rtspclient
+
+// Vp9RtpParser depacketizes VP9 (draft-ietf-payload-vp9), in flexible and
+// non-flexible mode. Every layer frame is delivered on its own.
+type Vp9RtpParser struct {
+	assembler  frameAssembler
+	isKeyFrame bool
+}
+
+// vp9DescriptorLen parses the VP9 payload descriptor and returns its size,
+// or 0 if it is malformed.
+func vp9DescriptorLen(rtpData []byte) int {
+	if len(rtpData) < 1 {
+		return 0
+	}
+	flags := rtpData[0]
+	pos := 1
+	if 0 != flags&0x80 {
+		// I: picture ID, 15 bits if M is set
+		if len(rtpData) < pos+1 {
+			return 0
+		}
+		if 0 != rtpData[pos]&0x80 {
+			pos += 2
+		} else {
+			pos++
+		}
+	}
+	if 0 != flags&0x20 {
+		// L: layer indices, plus TL0PICIDX in non-flexible mode
+		pos++
+		if 0 == flags&0x10 {
+			pos++
+		}
+	}
+	if 0 != flags&0x10 && 0 != flags&0x40 {
+		// F and P: up to three reference indices
+		for i := 0; i < 3; i++ {
+			if len(rtpData) < pos+1 {
+				return 0
+			}
+			more := 0 != rtpData[pos]&0x01
+			pos++
+			if !more {
+				break
+			}
+		}
+	}
+	if 0 != flags&0x02 {
+		// V: scalability structure
+		if len(rtpData) < pos+1 {
+			return 0
+		}
+		ss := rtpData[pos]
+		pos++
+		if 0 != ss&0x10 {
+			// Y: resolution of every spatial layer
+			pos += 4 * (int(ss>>5) + 1)
+		}
+		if 0 != ss&0x08 {
+			// G: picture group description
+			if len(rtpData) < pos+1 {
+				return 0
+			}
+			pictureCount := int(rtpData[pos])
+			pos++
+			for i := 0; i < pictureCount; i++ {
+				if len(rtpData) < pos+1 {
+					return 0
+				}
+				pos += 1 + int(rtpData[pos]>>2&0x03)
+			}
+		}
+	}
+	if len(rtpData) <= pos {
+		return 0
+	}
+	return pos
+}
+
+func (rtpParser *Vp9RtpParser) SplitHeader(src []byte) (isCompletesFrame bool, header []byte, payload []byte) {
+	rtpData := src[RtpHeaderLen:]
+	seq := rtpSequence(src)
+	descriptorLen := vp9DescriptorLen(rtpData)
+	if 0 == descriptorLen {
+		rtpParser.assembler.started = false
+		return false, src, src[len(src):]
+	}
+
+	flags := rtpData[0]
+	if 0 != flags&0x08 {
+		// B: beginning of a frame, a key frame if not inter predicted in
+		// the base spatial layer
+		rtpParser.assembler.begin(seq)
+		spatialID := byte(0)
+		if 0 != flags&0x20 {
+			spatialID = rtpData[1+vp9PictureIDLen(rtpData)] >> 1 & 0x07
+		}
+		rtpParser.isKeyFrame = 0 == flags&0x40 && 0 == spatialID
+	}
+	rtpParser.assembler.push(seq, rtpData[descriptorLen:])
+
+	if 0 == flags&0x04 {
+		// E: the frame continues
+		return false, src, src[len(src):]
+	}
+	frame := rtpParser.assembler.finish()
+	return true, src[:RtpHeaderLen], frame
+}
+
+func vp9PictureIDLen(rtpData []byte) int {
+	if 0 == rtpData[0]&0x80 {
+		return 0
+	}
+	if 0 != rtpData[1]&0x80 {
+		return 2
+	}
+	return 1
+}
+
+func (rtpParser *Vp9RtpParser) ParsingRtp(header []byte, payload []byte) (naluHeaderSize int, naluSize int) {
+	return 0, len(payload)
+}
+
+// FrameInfo marks key frames.
+func (rtpParser *Vp9RtpParser) FrameInfo(data *RtspData) {
+	data.IsKeyFrame = rtpParser.isKeyFrame
+}
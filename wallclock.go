@@ -0,0 +1,123 @@
+package rtspclient
+
+import (
+	"strconv"
+	"strings"
+	"time"
+)
+
+// wallClock maps the rtp timestamps of a track to wall-clock time. Sender
+// reports give the mapping of the sender, until the first one arrives the
+// RTP-Info of PLAY or the arrival of the first packet anchors it.
+type wallClock struct {
+	clockRate  int
+	anchorRTP  uint32
+	anchorTime time.Time
+	anchored   bool
+	fromSR     bool
+}
+
+func (clock *wallClock) setSenderReport(report *RtcpSenderReport) {
+	clock.anchorRTP = report.RTPTime
+	clock.anchorTime = report.Time()
+	clock.anchored = true
+	clock.fromSR = true
+}
+
+// setRtpInfo anchors rtptime of the RTP-Info header at the arrival of the
+// PLAY response.
+func (clock *wallClock) setRtpInfo(rtpTime uint32, arrival time.Time) {
+	if clock.fromSR {
+		return
+	}
+	clock.anchorRTP = rtpTime
+	clock.anchorTime = arrival
+	clock.anchored = true
+}
+
+// time returns the wall-clock time of an rtp timestamp that arrived at
+// arrival.
+func (clock *wallClock) time(timestamp uint32, arrival time.Time) time.Time {
+	if 0 >= clock.clockRate {
+		return arrival
+	}
+	if !clock.anchored {
+		clock.anchorRTP = timestamp
+		clock.anchorTime = arrival
+		clock.anchored = true
+	}
+	// signed difference, timestamps before the anchor are valid too
+	delta := int64(int32(timestamp - clock.anchorRTP))
+	mapped := clock.anchorTime.Add(time.Duration(delta * int64(time.Second) / int64(clock.clockRate)))
+	if 1<<30 < delta {
+		// follow the stream before the difference passes 2^31
+		clock.anchorRTP = timestamp
+		clock.anchorTime = mapped
+	}
+	return mapped
+}
+
+// RtpInfo is an entry of the RTP-Info header of a PLAY response.
+type RtpInfo struct {
+	URL        string
+	Seq        uint16
+	HasSeq     bool
+	RtpTime    uint32
+	HasRtpTime bool
+}
+
+// parsingRtpInfo decodes the value of an RTP-Info header.
+func parsingRtpInfo(value string) []RtpInfo {
+	var infos []RtpInfo
+	for _, entry := range strings.Split(value, ",") {
+		info := RtpInfo{}
+		for _, param := range strings.Split(entry, ";") {
+			keyValue := strings.SplitN(strings.TrimSpace(param), "=", 2)
+			if 2 != len(keyValue) {
+				continue
+			}
+			switch strings.ToLower(keyValue[0]) {
+			case "url":
+				info.URL = keyValue[1]
+			case "seq":
+				if seq, err := strconv.ParseUint(keyValue[1], 10, 16); nil == err {
+					info.Seq = uint16(seq)
+					info.HasSeq = true
+				}
+			case "rtptime":
+				if rtpTime, err := strconv.ParseUint(keyValue[1], 10, 32); nil == err {
+					info.RtpTime = uint32(rtpTime)
+					info.HasRtpTime = true
+				}
+			}
+		}
+		if "" != info.URL || info.HasRtpTime {
+			infos = append(infos, info)
+		}
+	}
+	return infos
+}
+
+// applyRtpInfo anchors the wall clocks of the tracks named in the RTP-Info of
+// a PLAY response.
+func (session *RtspClientSession) applyRtpInfo(infos []RtpInfo, arrival time.Time) {
+	if nil == session.sdpInfo {
+		return
+	}
+	for index, media := range session.sdpInfo.Medias {
+		rtpParser, ok := session.channels()[index*2]
+		if !ok {
+			continue
+		}
+		for _, info := range infos {
+			matched := 1 == len(infos) && 1 == len(session.sdpInfo.Medias)
+			if "" != media.TrackURL && strings.HasSuffix(info.URL, media.TrackURL) {
+				matched = true
+			}
+			if matched && info.HasRtpTime {
+				rtpParser.clock.setRtpInfo(info.RtpTime, arrival)
+				break
+			}
+		}
+	}
+}
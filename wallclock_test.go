@@ -0,0 +1,90 @@
+package rtspclient
+
+import (
+	"testing"
+	"time"
+)
+
+func TestWallClock(t *testing.T) {
+	clock := wallClock{clockRate: 90000}
+	arrival := time.Unix(1000, 0)
+
+	// the first packet anchors the clock
+	if !clock.time(90000, arrival).Equal(arrival) {
+		t.Errorf("first packet time %v", clock.time(90000, arrival))
+	}
+	if !clock.time(90000+45000, arrival).Equal(arrival.Add(500 * time.Millisecond)) {
+		t.Errorf("later packet time")
+	}
+
+	// RTP-Info replaces the anchor, across the timestamp wrap
+	clock.setRtpInfo(0xffffffff-8999, arrival)
+	if !clock.time(81000, arrival).Equal(arrival.Add(time.Second)) {
+		t.Errorf("wrapped packet time %v", clock.time(81000, arrival))
+	}
+
+	// sender reports win over RTP-Info
+	report := &RtcpSenderReport{NTPTime: 0xe2d20e0080000000, RTPTime: 1000}
+	clock.setSenderReport(report)
+	clock.setRtpInfo(0, arrival)
+	if !clock.time(0xffffffff-7999, arrival).Equal(report.Time().Add(-100 * time.Millisecond)) {
+		t.Errorf("sender report time %v", clock.time(0xffffffff-7999, arrival))
+	}
+
+	// no clock rate, the arrival time
+	clock = wallClock{}
+	if !clock.time(1234, arrival).Equal(arrival) {
+		t.Errorf("arrival time")
+	}
+}
+
+func TestWallClockLongRunning(t *testing.T) {
+	clock := wallClock{clockRate: 90000}
+	start := time.Unix(1000, 0)
+	clock.time(0, start)
+	// a frame every 10 minutes for 14 hours, passing 2^31 and 2^32 units
+	for minutes := 10; minutes <= 14*60; minutes += 10 {
+		elapsed := time.Duration(minutes) * time.Minute
+		timestamp := uint32(uint64(minutes) * 60 * 90000)
+		if mapped := clock.time(timestamp, start.Add(elapsed)); !mapped.Equal(start.Add(elapsed)) {
+			t.Fatalf("time after %v is %v", elapsed, mapped.Sub(start))
+		}
+	}
+}
+
+func TestParsingRtpInfo(t *testing.T) {
+	response := "RTSP/1.0 200 OK\r\n" +
+		"CSeq: 5\r\n" +
+		"Rtp-Info: url=rtsp://192.168.1.2:554/live/trackID=0;seq=1234;rtptime=3000000000,url=rtsp://192.168.1.2:554/live/trackID=1;seq=7\r\n\r\n"
+	context := &RtspResponseContext{}
+	if err := ParserRtspResponse([]byte(response), context); nil != err {
+		t.Fatal(err)
+	}
+	if 2 != len(context.rtpInfos) {
+		t.Fatalf("rtp infos %+v", context.rtpInfos)
+	}
+	first := context.rtpInfos[0]
+	if "rtsp://192.168.1.2:554/live/trackID=0" != first.URL || !first.HasSeq || 1234 != first.Seq || !first.HasRtpTime || 3000000000 != first.RtpTime {
+		t.Errorf("first rtp info %+v", first)
+	}
+	if second := context.rtpInfos[1]; !second.HasSeq || second.HasRtpTime {
+		t.Errorf("second rtp info %+v", second)
+	}
+
+	session := NewRtspClientSession(nil, nil)
+	session.sdpInfo = &SDPInfo{Medias: []MediaSubsession{
+		{CodecName: "H264", RtpTimestampFrequency: 90000, TrackURL: "trackID=0"},
+		{CodecName: "PCMA", RtpTimestampFrequency: 8000, TrackURL: "trackID=1"},
+	}}
+	for index, media := range session.sdpInfo.Medias {
+		session.addTrack(index*2, newRtpParser(media), media)
+	}
+	arrival := time.Unix(1000, 0)
+	session.applyRtpInfo(context.rtpInfos, arrival)
+	if clock := session.channels()[0].clock; !clock.anchored || 3000000000 != clock.anchorRTP || !clock.anchorTime.Equal(arrival) {
+		t.Errorf("video clock %+v", clock)
+	}
+	if session.channels()[2].clock.anchored {
+		t.Errorf("audio clock anchored without rtptime")
+	}
+}